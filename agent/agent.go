@@ -0,0 +1,25 @@
+// Package agent contains the players that can play a game of Tri Peaks. A
+// player only sees an Observation of the game, never the hidden cards.
+package agent
+
+import "github.com/MatiasLyyra/TriPeaks/game"
+
+// Player chooses the next move of a game
+type Player interface {
+	// Move returns the move to play in the observed position. The game must
+	// not be over.
+	Move(obs *game.Observation) game.Move
+}
+
+// Resetter is implemented by players that keep state between the moves of a
+// game. Reset is called before a new game is started.
+type Resetter interface {
+	Reset()
+}
+
+// Reset resets the player if it implements Resetter
+func Reset(player Player) {
+	if r, ok := player.(Resetter); ok {
+		r.Reset()
+	}
+}
@@ -0,0 +1,88 @@
+package agent
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/MatiasLyyra/TriPeaks/deck"
+	"github.com/MatiasLyyra/TriPeaks/game"
+)
+
+// Human asks for the moves on a text stream. A move is entered as a slot
+// number, a card such as "7h", "d" to draw or "s" to surrender. The player
+// surrenders when the input ends.
+type Human struct {
+	in  *bufio.Scanner
+	out io.Writer
+}
+
+func NewHuman(in io.Reader, out io.Writer) *Human {
+	return &Human{
+		in:  bufio.NewScanner(in),
+		out: out,
+	}
+}
+
+func (p *Human) Move(obs *game.Observation) game.Move {
+	legals, _ := obs.LegalMoves()
+	cards := obs.Cards()
+	options := make([]string, 0, len(legals))
+	for _, pos := range legals {
+		if pos == -1 {
+			options = append(options, "d (draw)")
+		} else {
+			options = append(options, fmt.Sprintf("%d (%s)", pos, cards[pos].Short()))
+		}
+	}
+	fmt.Fprintf(p.out, "Legal moves: %s\n", strings.Join(options, ", "))
+	for {
+		fmt.Fprint(p.out, "> ")
+		if !p.in.Scan() {
+			fmt.Fprintln(p.out)
+			return game.Move{Kind: game.MoveSurrender}
+		}
+		move, err := parseMove(strings.TrimSpace(p.in.Text()), cards, legals)
+		if err != nil {
+			fmt.Fprintf(p.out, "%s\n", err)
+			continue
+		}
+		return move
+	}
+}
+
+func parseMove(input string, cards game.TriPeaksDeck, legals []int) (game.Move, error) {
+	switch strings.ToLower(input) {
+	case "d", "draw":
+		input = "-1"
+	case "s", "surrender":
+		return game.Move{Kind: game.MoveSurrender}, nil
+	}
+	pos, err := strconv.Atoi(input)
+	if err != nil {
+		card, cardErr := deck.ParseCard(input)
+		if cardErr != nil {
+			return game.Move{}, fmt.Errorf("unknown move %q", input)
+		}
+		pos = -2
+		for _, legal := range legals {
+			if legal != -1 && cards[legal].Card == card {
+				pos = legal
+			}
+		}
+		if pos == -2 {
+			return game.Move{}, fmt.Errorf("%s cannot be played", card.Short())
+		}
+	}
+	for _, legal := range legals {
+		if legal == pos {
+			return game.MoveFromPos(pos), nil
+		}
+	}
+	if pos == -1 {
+		return game.Move{}, fmt.Errorf("the stock is empty")
+	}
+	return game.Move{}, fmt.Errorf("slot %d cannot be played", pos)
+}
@@ -0,0 +1,149 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"time"
+
+	"github.com/MatiasLyyra/TriPeaks/deck"
+	"github.com/MatiasLyyra/TriPeaks/game"
+	"github.com/MatiasLyyra/TriPeaks/mcts"
+)
+
+// MCTS searches every move with mcts.SearchParallel and plays the move with
+// the highest score
+type MCTS struct {
+	mcts.Options
+	// ThinkTime limits the time spent on each move if not 0. The threads then
+	// search determinizations until the time runs out.
+	ThinkTime time.Duration
+	// ReuseTree continues the search of the next move from the subtrees of
+	// the move played. It relies on every move returned by Move being played.
+	ReuseTree bool
+	// Final chooses the move from the search results, mcts.MaxScore if nil
+	Final mcts.FinalPolicy
+	// Out receives the visits and the mean reward of every move searched if
+	// not nil
+	Out io.Writer
+
+	moves    int64
+	stats    mcts.SearchStats
+	random   *rand.Rand
+	searcher *mcts.Searcher
+	last     *game.Observation
+	lastMove game.Move
+}
+
+func NewMCTS(threads, determinizations, trajectories int, eval mcts.SimulationtEval) *MCTS {
+	return &MCTS{
+		Options: mcts.Options{
+			Threads:          threads,
+			Determinizations: determinizations,
+			Trajectories:     trajectories,
+			Eval:             eval,
+		},
+	}
+}
+
+// Reset restarts the seeds of the searches so that a seeded player plays the
+// same game again
+func (p *MCTS) Reset() {
+	p.moves = 0
+	p.random = nil
+	p.last = nil
+	if p.searcher != nil {
+		p.searcher.Reset()
+	}
+}
+
+// Search returns the scores of the moves summed over all threads and the
+// statistics of the search
+func (p *MCTS) Search(obs *game.Observation) (mcts.SearchResults, mcts.SearchStats) {
+	options := p.Options
+	// Every move of a seeded game is searched with a different seed
+	if options.Seed != 0 {
+		options.Seed += p.moves
+	}
+	p.moves++
+	ctx := context.Background()
+	if p.ThinkTime > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.ThinkTime)
+		defer cancel()
+		options.Continuous = true
+	}
+	if p.searcher == nil {
+		p.searcher = mcts.NewSearcher(options)
+	}
+	p.searcher.Options = options
+	if p.ReuseTree && p.last != nil {
+		p.searcher.Advance(p.lastMove, revealedCards(p.last, obs, p.lastMove))
+	} else {
+		p.searcher.Reset()
+	}
+	p.last = nil
+	return p.searcher.Search(ctx, obs)
+}
+
+func (p *MCTS) Move(obs *game.Observation) game.Move {
+	results, stats := p.Search(obs)
+	p.stats = stats
+	final := p.Final
+	if final == nil {
+		final = mcts.MaxScore{}
+	}
+	if p.random == nil {
+		seed := p.Seed
+		if seed == 0 {
+			seed = time.Now().UTC().UnixNano()
+		}
+		p.random = rand.New(rand.NewSource(seed))
+	}
+	action, ok := final.Choose(results, p.random)
+	if !ok {
+		// Nothing was searched before the time ran out
+		moves, _ := obs.LegalMoves()
+		action = moves[p.random.Intn(len(moves))]
+	}
+	if p.Out != nil {
+		for _, result := range results {
+			fmt.Fprintf(p.Out, "Move %d Visits %d Mean %f\n", result.Move, result.Visits, result.Mean)
+		}
+	}
+	move := game.MoveFromPos(action)
+	p.last = obs
+	p.lastMove = move
+	return move
+}
+
+// Stats returns the statistics of the search of the last move
+func (p *MCTS) Stats() mcts.SearchStats {
+	return p.stats
+}
+
+// Roots returns the roots of the trees of the last search, one for each
+// determinization searched
+func (p *MCTS) Roots() []*mcts.Node {
+	if p.searcher == nil {
+		return nil
+	}
+	return p.searcher.Roots()
+}
+
+// revealedCards returns the cards the move turned face up between the two
+// observations
+func revealedCards(before, after *game.Observation, move game.Move) []deck.Card {
+	if move.Kind == game.MoveDraw {
+		return []deck.Card{after.Discard()}
+	}
+	revealed := make([]deck.Card, 0, 2)
+	afterCards := after.Cards()
+	for pos, card := range before.Cards() {
+		if card.FaceDown && !afterCards[pos].FaceDown {
+			revealed = append(revealed, afterCards[pos].Card)
+		}
+	}
+	return revealed
+}
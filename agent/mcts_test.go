@@ -0,0 +1,51 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/MatiasLyyra/TriPeaks/deck"
+	"github.com/MatiasLyyra/TriPeaks/game"
+	"github.com/MatiasLyyra/TriPeaks/mcts"
+)
+
+func TestISMCTSReuseTreePlaysLegalMoves(t *testing.T) {
+	tests := []struct {
+		name         string
+		threads      int
+		treeParallel bool
+	}{
+		{"single thread", 1, false},
+		{"root parallel", 2, false},
+		{"tree parallel", 2, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			player := NewMCTS(test.threads, 4, 25, mcts.ScoreSigmoidEval)
+			player.Mode = mcts.ISMCTS
+			player.ReuseTree = true
+			player.TreeParallel = test.treeParallel
+			player.Seed = 5
+			stock := deck.New()
+			stock.ShuffleSeed(5)
+			tri := game.NewTripeaks(*stock, game.DefaultRules())
+			for !tri.GameOver() {
+				legal, _ := tri.LegalMoves()
+				move := player.Move(tri.Observe())
+				pos := move.Pos
+				if move.Kind == game.MoveDraw {
+					pos = -1
+				}
+				found := false
+				for _, m := range legal {
+					found = found || m == pos
+				}
+				if !found {
+					t.Fatalf("played %s, legal moves are %v", move, legal)
+				}
+				if !tri.Play(move) {
+					t.Fatalf("failed to play %s", move)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package agent
+
+import (
+	"math/rand"
+
+	"github.com/MatiasLyyra/TriPeaks/game"
+)
+
+// Random plays a uniformly random legal move
+type Random struct {
+	// Rand is the source of the moves, the global source of math/rand is
+	// used if nil
+	Rand *rand.Rand
+}
+
+func NewRandom(seed int64) *Random {
+	return &Random{
+		Rand: rand.New(rand.NewSource(seed)),
+	}
+}
+
+func (p *Random) Move(obs *game.Observation) game.Move {
+	legals, _ := obs.LegalMoves()
+	var ind int
+	if p.Rand != nil {
+		ind = p.Rand.Intn(len(legals))
+	} else {
+		ind = rand.Intn(len(legals))
+	}
+	return game.MoveFromPos(legals[ind])
+}
@@ -1,15 +1,18 @@
 package main
 
 import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
 	"fmt"
 	"io"
 	"log"
-	"math"
-	"math/rand"
 	"os"
-	"runtime"
+	"path/filepath"
+	"strings"
 	"time"
 
+	"github.com/MatiasLyyra/TriPeaks/agent"
 	"github.com/MatiasLyyra/TriPeaks/deck"
 	"github.com/MatiasLyyra/TriPeaks/game"
 
@@ -23,6 +26,25 @@ type BenchmarkOptions struct {
 	Determinizations int
 	Trajectories     int
 	Eval             mcts.SimulationtEval
+	// ThinkTime gives every move the same time instead of a fixed number of
+	// determinizations if not 0
+	ThinkTime time.Duration
+	// TreeParallel makes the threads search shared trees
+	TreeParallel bool
+	// ReuseTree continues every search from the subtree of the last move
+	ReuseTree bool
+	// Transpositions is the size of the transposition table, none if 0
+	Transpositions int
+	// Policy selects the nodes to descend to, mcts.DefaultPolicy if nil
+	Policy mcts.SelectionPolicy
+	// Final chooses the move to play, mcts.MaxScore if nil
+	Final mcts.FinalPolicy
+	// Rollout plays the games out, mcts.UniformRollout if nil
+	Rollout mcts.RolloutPolicy
+	// Mode selects the search algorithm
+	Mode mcts.SearchMode
+	// Rules used for the games, game.DefaultRules if nil
+	Rules *game.Rules
 }
 type ToCSV interface {
 }
@@ -34,226 +56,369 @@ type BenchmarkResult struct {
 	GamesWon         int
 	CardsCleared     int
 	Points           int
+	// OracleWins counts the deals that can be won with perfect information
+	// and OracleUnknown the deals the solver gave up on
+	OracleWins    int
+	OracleUnknown int
+	// Seeds are the seeds of the deals played in the order they were dealt
+	// and Won tells which of them were won
+	Seeds []uint64
+	Won   []bool
+	// Scores and ClearRates are those of every game in the order of Won, the
+	// clear rate being the fraction of the tableau cleared
+	Scores     []int
+	ClearRates []float64
+	Draws      int
+	// LongestStreak is the longest streak of all games
+	LongestStreak int
+	PeaksCleared  int
+	Moves         int
+	MoveTime      time.Duration
+	SearchTotals
+}
+
+// searchStats is implemented by the players that report the statistics of
+// their last search
+type searchStats interface {
+	Stats() mcts.SearchStats
 }
 
 func WriteCsv(results []BenchmarkResult, w io.Writer) {
-	_, err := w.Write([]byte("name,n,determinizations,trajectories,games_won,cards_cleared,points\n"))
-	if err != nil {
+	writer := csv.NewWriter(w)
+	writer.Write(strings.Split("name,n,determinizations,trajectories,games_won,cards_cleared,points,oracle_wins,oracle_unknown,"+
+		"median_score,median_clear_rate,draws,longest_streak,peaks_cleared,moves,move_ms,"+
+		"searches,iterations,nodes,max_depth,avg_depth,avg_rollout,avg_spread,"+
+		"search_ms,selection_ms,expansion_ms,simulation_ms,backpropagation_ms", ","))
+	for _, r := range results {
+		depth, rollout, spread := r.averages()
+		writer.Write(append([]string{r.Name}, formatFields("%d,%d,%d,%d,%d,%d,%d,%d,%.1f,%.4f,%d,%d,%d,%d,%d,%d,%d,%d,%d,%.2f,%.2f,%.4f,%d,%d,%d,%d,%d",
+			r.N, r.Determinizations, r.Trajectories, r.GamesWon, r.CardsCleared, r.Points, r.OracleWins, r.OracleUnknown,
+			medianInts(r.Scores), median(r.ClearRates), r.Draws, r.LongestStreak, r.PeaksCleared, r.Moves, milliseconds(r.MoveTime),
+			r.Searches, r.Iterations, r.Nodes, r.MaxDepth, depth, rollout, spread,
+			milliseconds(r.SearchTime), milliseconds(r.Selection), milliseconds(r.Expansion),
+			milliseconds(r.Simulation), milliseconds(r.Backpropagation))...))
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
 		log.Printf("write error: %s", err)
 	}
-	for _, r := range results {
-		csv := fmt.Sprintf("%s,%d,%d,%d,%d,%d,%d\n", r.Name, r.N, r.Determinizations, r.Trajectories, r.GamesWon, r.CardsCleared, r.Points)
-		_, err = w.Write([]byte(csv))
-		if err != nil {
-			log.Printf("write error: %s", err)
+}
+
+// formatFields formats the values with the comma separated format and
+// splits them into CSV fields. Only for values that cannot contain commas,
+// the names are added as fields of their own for csv.Writer to quote.
+func formatFields(format string, values ...interface{}) []string {
+	return strings.Split(fmt.Sprintf(format, values...), ",")
+}
+
+func milliseconds(d time.Duration) int64 {
+	return int64(d / time.Millisecond)
+}
+
+// playGame plays the deal of the seed
+func playGame(options BenchmarkOptions, player agent.Player, seed uint64) GameRecord {
+	record := GameRecord{
+		Agent: options.Name,
+		Seed:  seed,
+	}
+	rules := game.DefaultRules()
+	if options.Rules != nil {
+		rules = *options.Rules
+	}
+	stock := deck.New()
+	stock.ShuffleSeed(seed)
+	code, err := stock.Code()
+	if err != nil {
+		log.Fatalf("invalid deck with seed %d: %s", seed, err)
+	}
+	record.Deal = code
+	triGame := game.NewTripeaks(*stock, rules)
+	winnable, known := oracleWinnable(seed, triGame)
+	record.OracleWinnable = winnable
+	record.OracleUnknown = !known
+	agent.Reset(player)
+	for !triGame.GameOver() {
+		start := time.Now()
+		move := player.Move(triGame.Observe())
+		record.Moves = append(record.Moves, MoveRecord{Move: move.String(), Time: time.Since(start)})
+		if !triGame.Play(move) {
+			log.Fatalf("%s played an illegal move: %s", options.Name, move)
+		}
+		if searcher, ok := player.(searchStats); ok {
+			record.Search.addStats(searcher.Stats(), move)
+		}
+		if move.Kind == game.MoveDraw {
+			record.Draws++
+		}
+		if triGame.Streak > record.LongestStreak {
+			record.LongestStreak = triGame.Streak
+		}
+		if move.Kind == game.MoveSurrender {
+			break
 		}
 	}
+	record.Won = triGame.CardsLeft == 0
+	record.Score = triGame.Score
+	record.CardsCleared = len(triGame.Cards) - triGame.CardsLeft
+	record.Cards = len(triGame.Cards)
+	record.PeaksCleared = peaksCleared(triGame)
+	return record
 }
 
-type AiFunc func(*game.TriPeaks, BenchmarkOptions) int
+func peaksCleared(tri *game.TriPeaks) int {
+	cleared := 0
+	for _, peak := range tri.Layout.Peaks {
+		if tri.Cards[peak].Removed {
+			cleared++
+		}
+	}
+	return cleared
+}
 
-func benchmarkSearch(options BenchmarkOptions, ai AiFunc) BenchmarkResult {
+// aggregate sums the records of the agent in the order of the seeds
+func aggregate(options BenchmarkOptions, seeds []uint64, records map[recordKey]GameRecord) BenchmarkResult {
 	r := BenchmarkResult{
 		Name:             options.Name,
 		Determinizations: options.Determinizations * options.Threads,
 		Trajectories:     options.Trajectories,
-		N:                options.N,
-	}
-	for i := 0; i < options.N; i++ {
-		stock := deck.New()
-		stock.Shuffle()
-		triGame := game.NewTripeaks(*stock)
-		for !triGame.GameOver() {
-			move := ai(triGame, options)
-			if move == -1 {
-				triGame.Draw()
-			} else {
-				triGame.Select(move)
-			}
+	}
+	for _, seed := range seeds {
+		record, found := records[recordKey{options.Name, seed}]
+		if !found {
+			continue
 		}
-		r.Points += triGame.Score
-		r.CardsCleared += 28 - triGame.CardsLeft
-		if triGame.CardsLeft == 0 {
+		r.N++
+		r.Points += record.Score
+		r.CardsCleared += record.CardsCleared
+		if record.Won {
 			r.GamesWon++
 		}
-		fmt.Printf("%s progress: %.2f %%\n", options.Name, math.Round(float64(i)/float64(options.N)*10000)/100)
+		if record.OracleWinnable {
+			r.OracleWins++
+		}
+		if record.OracleUnknown {
+			r.OracleUnknown++
+		}
+		r.Seeds = append(r.Seeds, seed)
+		r.Won = append(r.Won, record.Won)
+		r.Scores = append(r.Scores, record.Score)
+		if record.Cards > 0 {
+			r.ClearRates = append(r.ClearRates, float64(record.CardsCleared)/float64(record.Cards))
+		}
+		r.Draws += record.Draws
+		if record.LongestStreak > r.LongestStreak {
+			r.LongestStreak = record.LongestStreak
+		}
+		r.PeaksCleared += record.PeaksCleared
+		r.Moves += len(record.Moves)
+		r.MoveTime += record.moveTime()
+		r.SearchTotals.add(record.Search)
 	}
 	return r
 }
-func random(triGame *game.TriPeaks, options BenchmarkOptions) int {
-	legals, _ := triGame.LegalMoves()
-	return legals[rand.Intn(len(legals))]
+
+// mctsPlayer creates the MCTS player described by the options
+func mctsPlayer(options BenchmarkOptions) agent.Player {
+	player := agent.NewMCTS(options.Threads, options.Determinizations, options.Trajectories, options.Eval)
+	player.ThinkTime = options.ThinkTime
+	player.TreeParallel = options.TreeParallel
+	player.ReuseTree = options.ReuseTree
+	player.Transpositions = options.Transpositions
+	player.Policy = options.Policy
+	player.Final = options.Final
+	player.Rollout = options.Rollout
+	player.Mode = options.Mode
+	return player
 }
-func mctsSearch(triGame *game.TriPeaks, options BenchmarkOptions) int {
-	movesMap := make(map[int]float64)
-	movesChan := make(chan []mcts.SearchResult, 2)
-	for i := 0; i < options.Threads; i++ {
-		go func() {
-			movesChan <- mcts.Search(triGame, options.Determinizations, options.Trajectories, options.Eval)
-		}()
-	}
-	highestScore := -1.0
-	action := -1
-	i := 0
-	for moves := range movesChan {
-		for _, move := range moves {
-			if _, exists := movesMap[move.Move]; exists {
-				movesMap[move.Move] += move.Score
-			} else {
-				movesMap[move.Move] = move.Score
-			}
-			if movesMap[move.Move] > highestScore {
-				highestScore = movesMap[move.Move]
-				action = move.Move
-			}
+
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == "replay" {
+		replayMain(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "report" {
+		reportMain(os.Args[2:])
+		return
+	}
+	configPath := flag.String("config", "", "experiment file in JSON, the built-in experiment if empty")
+	games := flag.Int("games", 0, "number of games every agent plays, overrides the config")
+	threads := flag.Int("threads", 0, "threads of every agent, overrides the config")
+	seed := flag.Int64("seed", 0, "seed of the deals, overrides the config")
+	output := flag.String("out", "", "directory the results are written to, overrides the config")
+	agents := flag.String("agents", "", "comma separated names of the agents to run, all if empty")
+	baseline := flag.String("baseline", "", "agent the others are compared to, overrides the config")
+	determinizations := flag.Int("determinizations", 0, "determinizations of every agent, overrides the config")
+	trajectories := flag.Int("trajectories", 0, "trajectories of every agent, overrides the config")
+	printConfig := flag.Bool("print-config", false, "print the experiment as JSON and exit")
+	workers := flag.Int("workers", 0, "number of games played at once, overrides the config")
+	resume := flag.String("resume", "", "run directory of an interrupted run to continue, the flags other than -workers are ignored")
+	flag.Parse()
+
+	if *resume != "" {
+		config, err := LoadConfig(filepath.Join(*resume, experimentFile))
+		if err != nil {
+			log.Fatalf("failed to load the experiment to resume: %s", err)
 		}
-		i++
-		if i >= options.Threads {
-			break
+		if *workers > 0 {
+			config.Workers = *workers
 		}
+		runExperiment(config, *resume)
+		return
 	}
-	return action
-}
-
-func main() {
-	runtime.GOMAXPROCS(10)
-	results := make([]BenchmarkResult, 0, 10)
 
-	rand.Seed(time.Now().UnixNano())
-	options := BenchmarkOptions{
-		Name:             "Random",
-		N:                500,
-		Threads:          1,
-		Determinizations: 0,
-		Trajectories:     0,
-		Eval:             nil,
-	}
-	results = append(results, benchmarkSearch(options, random))
+	config := DefaultConfig()
+	if *configPath != "" {
+		var err error
+		if config, err = LoadConfig(*configPath); err != nil {
+			log.Fatalf("failed to load config: %s", err)
+		}
+	}
+	if *games > 0 {
+		config.Games = *games
+		config.Seeds = nil
+	}
+	if *seed != 0 {
+		config.Seed = *seed
+	}
+	if *output != "" {
+		config.Output = *output
+	}
+	if *baseline != "" {
+		config.Baseline = *baseline
+	}
+	if *workers > 0 {
+		config.Workers = *workers
+	}
+	if *agents != "" {
+		selected := make(map[string]bool)
+		for _, name := range strings.Split(*agents, ",") {
+			selected[strings.TrimSpace(name)] = true
+		}
+		kept := config.Agents[:0]
+		for _, a := range config.Agents {
+			if selected[a.Name] {
+				kept = append(kept, a)
+				delete(selected, a.Name)
+			}
+		}
+		for name := range selected {
+			log.Fatalf("unknown agent %q", name)
+		}
+		config.Agents = kept
+		// The baseline of the config falls back to the first agent when it
+		// was not selected, a baseline given with -baseline must be selected
+		if *baseline == "" && findAgent(config.Agents, config.Baseline) == -1 {
+			config.Baseline = ""
+		}
+	}
+	for i := range config.Agents {
+		a := &config.Agents[i]
+		if a.Player == "random" {
+			continue
+		}
+		if *threads > 0 {
+			a.Threads = *threads
+		}
+		if *determinizations > 0 {
+			a.Determinizations = *determinizations
+		}
+		if *trajectories > 0 {
+			a.Trajectories = *trajectories
+		}
+	}
+	if *printConfig {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(config); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if err := config.Validate(); err != nil {
+		log.Fatalf("invalid config: %s", err)
+	}
+	if config.Seed == 0 {
+		config.Seed = time.Now().UnixNano()
+	}
 
-	options = BenchmarkOptions{
-		Name:             "LinearEval 1",
-		N:                500,
-		Threads:          10,
-		Determinizations: 1,
-		Trajectories:     1500,
-		Eval:             mcts.LinearEval,
-	}
-	results = append(results, benchmarkSearch(options, mctsSearch))
-	options = BenchmarkOptions{
-		Name:             "LinearEval 2",
-		N:                500,
-		Threads:          10,
-		Determinizations: 5,
-		Trajectories:     2500,
-		Eval:             mcts.LinearEval,
-	}
-	results = append(results, benchmarkSearch(options, mctsSearch))
-	options = BenchmarkOptions{
-		Name:             "LinearEval 3",
-		N:                500,
-		Threads:          10,
-		Determinizations: 10,
-		Trajectories:     3500,
-		Eval:             mcts.LinearEval,
-	}
-	results = append(results, benchmarkSearch(options, mctsSearch))
+	t := time.Now()
+	dir := filepath.Join(config.Output, fmt.Sprintf("benchmark_eval_%d_%02d_%02d_%02d_%02d_%02d", t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second()))
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		log.Fatalf("failed to create %s: %s", dir, err)
+	}
+	if err := writeConfig(filepath.Join(dir, experimentFile), config); err != nil {
+		log.Fatalf("failed to write the experiment: %s", err)
+	}
+	runExperiment(config, dir)
+}
 
-	options = BenchmarkOptions{
-		Name:             "BinaryEval 1",
-		N:                500,
-		Threads:          10,
-		Determinizations: 1,
-		Trajectories:     1500,
-		Eval:             mcts.BinaryEval,
-	}
-	results = append(results, benchmarkSearch(options, mctsSearch))
-	options = BenchmarkOptions{
-		Name:             "BinaryEval 2",
-		N:                500,
-		Threads:          10,
-		Determinizations: 5,
-		Trajectories:     2500,
-		Eval:             mcts.BinaryEval,
-	}
-	results = append(results, benchmarkSearch(options, mctsSearch))
-	options = BenchmarkOptions{
-		Name:             "BinaryEval 3",
-		N:                500,
-		Threads:          10,
-		Determinizations: 10,
-		Trajectories:     3500,
-		Eval:             mcts.BinaryEval,
-	}
-	results = append(results, benchmarkSearch(options, mctsSearch))
+// findAgent returns the index of the agent with the name, -1 if there is none
+func findAgent(agents []AgentConfig, name string) int {
+	for i, a := range agents {
+		if a.Name == name {
+			return i
+		}
+	}
+	return -1
+}
 
-	options = BenchmarkOptions{
-		Name:             "ScoreEval 1",
-		N:                500,
-		Threads:          10,
-		Determinizations: 1,
-		Trajectories:     1500,
-		Eval:             mcts.ScoreEval,
-	}
-	results = append(results, benchmarkSearch(options, mctsSearch))
-	options = BenchmarkOptions{
-		Name:             "ScoreEval 2",
-		N:                500,
-		Threads:          10,
-		Determinizations: 5,
-		Trajectories:     2500,
-		Eval:             mcts.ScoreEval,
-	}
-	results = append(results, benchmarkSearch(options, mctsSearch))
-	options = BenchmarkOptions{
-		Name:             "ScoreEval 3",
-		N:                500,
-		Threads:          10,
-		Determinizations: 10,
-		Trajectories:     3500,
-		Eval:             mcts.ScoreEval,
-	}
-	results = append(results, benchmarkSearch(options, mctsSearch))
+func findResult(results []BenchmarkResult, name string) (BenchmarkResult, bool) {
+	for _, r := range results {
+		if r.Name == name {
+			return r, true
+		}
+	}
+	return BenchmarkResult{}, false
+}
 
-	options = BenchmarkOptions{
-		Name:             "ScoreSigmoidEval 1",
-		N:                500,
-		Threads:          10,
-		Determinizations: 1,
-		Trajectories:     1500,
-		Eval:             mcts.ScoreSigmoidEval,
-	}
-	results = append(results, benchmarkSearch(options, mctsSearch))
-	options = BenchmarkOptions{
-		Name:             "ScoreSigmoidEval 2",
-		N:                500,
-		Threads:          10,
-		Determinizations: 5,
-		Trajectories:     2500,
-		Eval:             mcts.ScoreSigmoidEval,
-	}
-	results = append(results, benchmarkSearch(options, mctsSearch))
-	options = BenchmarkOptions{
-		Name:             "ScoreSigmoidEval 3",
-		N:                500,
-		Threads:          10,
-		Determinizations: 10,
-		Trajectories:     3500,
-		Eval:             mcts.ScoreSigmoidEval,
-	}
-	results = append(results, benchmarkSearch(options, mctsSearch))
+// saveResults writes the results and their comparisons to the run directory
+func saveResults(dir string, r []BenchmarkResult, comparisons []Comparison) {
+	path := filepath.Join(dir, resultsFile)
+	f, err := os.Create(path)
+	if err != nil {
+		log.Printf("failed to open / create file %s, writing to stdout\n", path)
+		WriteCsv(r, os.Stdout)
+		return
+	}
+	defer f.Close()
+	WriteCsv(r, f)
+	histogramPath := filepath.Join(dir, histogramFile)
+	if err := writeHistogram(histogramPath, r); err != nil {
+		log.Printf("failed to write %s: %s", histogramPath, err)
+	}
+	if len(comparisons) > 0 {
+		pairedPath := filepath.Join(dir, pairedFile)
+		if err := writePaired(pairedPath, comparisons); err != nil {
+			log.Printf("failed to write %s: %s", pairedPath, err)
+		}
+	}
+}
 
-	saveResults(results)
+func writePaired(path string, comparisons []Comparison) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return WritePairedCsv(comparisons, f)
 }
 
-func saveResults(r []BenchmarkResult) {
-	t := time.Now()
-	path := fmt.Sprintf("./benchmarks/benchmark_eval_%d_%02d_%02d_%02d_%02d.csv", t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute())
-	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY, 0666)
+func writeHistogram(path string, results []BenchmarkResult) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
 	defer f.Close()
+	return WriteHistogramCsv(results, f)
+}
+
+func writeConfig(path string, config Config) error {
+	f, err := os.Create(path)
 	if err != nil {
-		log.Printf("failed to open / create file %s, writing to stdout\n", path)
-		WriteCsv(r, os.Stdout)
-	} else {
-		WriteCsv(r, f)
+		return err
 	}
+	defer f.Close()
+	encoder := json.NewEncoder(f)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(config)
 }
@@ -0,0 +1,368 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/MatiasLyyra/TriPeaks/agent"
+	"github.com/MatiasLyyra/TriPeaks/game"
+	"github.com/MatiasLyyra/TriPeaks/mcts"
+)
+
+// Config describes an experiment, the agents to benchmark and the games they
+// play
+type Config struct {
+	// Games is the number of games every agent plays
+	Games int `json:"games"`
+	// Threads is used by the agents that do not set their own
+	Threads int `json:"threads"`
+	// Workers is the number of games played at once, each by its own copy
+	// of the agent. One if 0.
+	Workers int `json:"workers,omitempty"`
+	// Seed seeds the deals, every agent is dealt the same games. A random
+	// seed is picked if 0.
+	Seed int64 `json:"seed"`
+	// Seeds are the seeds of the decks to deal instead of Games deals from
+	// Seed if not empty
+	Seeds []uint64 `json:"seeds,omitempty"`
+	// Output is the directory the run directories with the results are
+	// created in
+	Output string        `json:"output"`
+	Agents []AgentConfig `json:"agents"`
+	// Baseline is the name of the agent the others are compared to on the
+	// same deals, the first agent if empty
+	Baseline string `json:"baseline,omitempty"`
+}
+
+// BaselineName returns the name of the baseline agent
+func (c Config) BaselineName() string {
+	if c.Baseline == "" && len(c.Agents) > 0 {
+		return c.Agents[0].Name
+	}
+	return c.Baseline
+}
+
+// AgentConfig describes a single agent. The names of the evals and policies
+// are the ones accepted by their parse functions below, the empty string
+// selecting the default of mcts.
+type AgentConfig struct {
+	Name string `json:"name"`
+	// Player is mcts or random, mcts if empty
+	Player           string `json:"player,omitempty"`
+	Eval             string `json:"eval,omitempty"`
+	Threads          int    `json:"threads,omitempty"`
+	Determinizations int    `json:"determinizations,omitempty"`
+	Trajectories     int    `json:"trajectories,omitempty"`
+	// ThinkTime is a duration such as 500ms, a fixed number of
+	// determinizations is searched if empty
+	ThinkTime      string      `json:"thinkTime,omitempty"`
+	TreeParallel   bool        `json:"treeParallel,omitempty"`
+	ReuseTree      bool        `json:"reuseTree,omitempty"`
+	Transpositions int         `json:"transpositions,omitempty"`
+	Policy         string      `json:"policy,omitempty"`
+	Final          string      `json:"final,omitempty"`
+	Rollout        string      `json:"rollout,omitempty"`
+	Mode           string      `json:"mode,omitempty"`
+	Rules          *game.Rules `json:"rules,omitempty"`
+}
+
+// LoadConfig reads an experiment from a JSON file. Only JSON is supported,
+// -print-config writes the built-in experiment in it to start from.
+func LoadConfig(path string) (Config, error) {
+	var config Config
+	f, err := os.Open(path)
+	if err != nil {
+		return config, err
+	}
+	defer f.Close()
+	decoder := json.NewDecoder(f)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&config); err != nil {
+		return config, fmt.Errorf("%s: %s", path, err)
+	}
+	return config, nil
+}
+
+// DealSeeds returns the seeds of the decks every agent is dealt
+func (c Config) DealSeeds() []uint64 {
+	if len(c.Seeds) > 0 {
+		return c.Seeds
+	}
+	random := rand.New(rand.NewSource(c.Seed))
+	seeds := make([]uint64, c.Games)
+	for i := range seeds {
+		seeds[i] = random.Uint64()
+	}
+	return seeds
+}
+
+// Validate checks that the agents can be created
+func (c Config) Validate() error {
+	if c.Games <= 0 && len(c.Seeds) == 0 {
+		return fmt.Errorf("no games to play")
+	}
+	names := make(map[string]bool)
+	for _, a := range c.Agents {
+		if a.Name == "" {
+			return fmt.Errorf("agent without a name")
+		}
+		if names[a.Name] {
+			return fmt.Errorf("agent %q listed twice", a.Name)
+		}
+		names[a.Name] = true
+		if _, _, err := a.options(c); err != nil {
+			return fmt.Errorf("agent %q: %s", a.Name, err)
+		}
+	}
+	if c.Baseline != "" && !names[c.Baseline] {
+		return fmt.Errorf("unknown baseline %q", c.Baseline)
+	}
+	return nil
+}
+
+// options returns the benchmark options of the agent and whether it is an
+// MCTS player
+func (a AgentConfig) options(c Config) (BenchmarkOptions, bool, error) {
+	options := BenchmarkOptions{
+		Name:             a.Name,
+		N:                c.Games,
+		Threads:          a.Threads,
+		Determinizations: a.Determinizations,
+		Trajectories:     a.Trajectories,
+		TreeParallel:     a.TreeParallel,
+		ReuseTree:        a.ReuseTree,
+		Transpositions:   a.Transpositions,
+		Rules:            a.Rules,
+	}
+	if len(c.Seeds) > 0 {
+		options.N = len(c.Seeds)
+	}
+	if options.Threads == 0 {
+		options.Threads = c.Threads
+	}
+	switch a.Player {
+	case "random":
+		options.Threads = 1
+		return options, false, nil
+	case "", "mcts":
+	default:
+		return options, false, fmt.Errorf("unknown player %q", a.Player)
+	}
+	var err error
+	if a.ThinkTime != "" {
+		if options.ThinkTime, err = time.ParseDuration(a.ThinkTime); err != nil {
+			return options, true, err
+		}
+	}
+	if options.ThinkTime <= 0 && options.Determinizations <= 0 {
+		return options, true, fmt.Errorf("no determinizations to search without a think time")
+	}
+	if options.Eval, err = parseEval(a.Eval); err != nil {
+		return options, true, err
+	}
+	if options.Policy, err = parsePolicy(a.Policy); err != nil {
+		return options, true, err
+	}
+	if options.Final, err = parseFinal(a.Final); err != nil {
+		return options, true, err
+	}
+	if options.Rollout, err = parseRollout(a.Rollout); err != nil {
+		return options, true, err
+	}
+	if options.Mode, err = parseMode(a.Mode); err != nil {
+		return options, true, err
+	}
+	return options, true, nil
+}
+
+// player creates the agent
+func (a AgentConfig) player(c Config) (BenchmarkOptions, agent.Player, error) {
+	options, isMCTS, err := a.options(c)
+	if err != nil {
+		return options, nil, err
+	}
+	if !isMCTS {
+		return options, &agent.Random{}, nil
+	}
+	if a.Rollout == "learned" {
+		options.Rollout = learnedRollout(a.Rules)
+	}
+	return options, mctsPlayer(options), nil
+}
+
+func parseEval(name string) (mcts.SimulationtEval, error) {
+	switch name {
+	case "", "score-sigmoid":
+		return mcts.ScoreSigmoidEval, nil
+	case "binary":
+		return mcts.BinaryEval, nil
+	case "linear":
+		return mcts.LinearEval, nil
+	case "score":
+		return mcts.ScoreEval, nil
+	case "score-log":
+		return mcts.ScoreLogEval, nil
+	}
+	return nil, fmt.Errorf("unknown eval %q", name)
+}
+
+func parsePolicy(name string) (mcts.SelectionPolicy, error) {
+	switch name {
+	case "", "ucb1":
+		return nil, nil
+	case "ucb1-tuned":
+		return mcts.UCB1Tuned{}, nil
+	case "puct":
+		return mcts.PUCT{C: 1.5, Prior: mcts.HeuristicPrior}, nil
+	case "thompson":
+		return mcts.Thompson{}, nil
+	}
+	return nil, fmt.Errorf("unknown policy %q", name)
+}
+
+func parseFinal(name string) (mcts.FinalPolicy, error) {
+	switch name {
+	case "", "score":
+		return nil, nil
+	case "visits":
+		return mcts.MaxVisits{}, nil
+	case "mean":
+		return mcts.MaxMean{}, nil
+	case "secure":
+		return mcts.SecureChild{A: 1}, nil
+	case "softmax":
+		return mcts.Softmax{Temperature: 0.05}, nil
+	}
+	return nil, fmt.Errorf("unknown final move policy %q", name)
+}
+
+// parseRollout returns nil for the learned policy, it is trained by
+// learnedRollout when the players are created
+func parseRollout(name string) (mcts.RolloutPolicy, error) {
+	switch name {
+	case "", "uniform", "learned":
+		return nil, nil
+	case "no-draw":
+		return mcts.NoDrawRollout{}, nil
+	case "lookahead":
+		return mcts.LookaheadRollout{Depth: 6}, nil
+	case "epsilon-greedy":
+		return mcts.EpsilonGreedy{Epsilon: 0.1}, nil
+	}
+	return nil, fmt.Errorf("unknown rollout policy %q", name)
+}
+
+var (
+	// learnedRollouts caches the trained policies by their rules
+	learnedRollouts   = make(map[game.Rules]mcts.Learned)
+	learnedRolloutsMu sync.Mutex
+	// trainLearned trains the learned policy, replaced by the tests
+	trainLearned = func(rules game.Rules) mcts.Learned {
+		return mcts.TrainLearned(20000, rules, 1)
+	}
+)
+
+// learnedRollout trains the learned policy for the rules the first time it
+// is needed
+func learnedRollout(rules *game.Rules) mcts.Learned {
+	trainRules := game.DefaultRules()
+	if rules != nil {
+		trainRules = *rules
+	}
+	learnedRolloutsMu.Lock()
+	defer learnedRolloutsMu.Unlock()
+	policy, trained := learnedRollouts[trainRules]
+	if !trained {
+		policy = trainLearned(trainRules)
+		learnedRollouts[trainRules] = policy
+	}
+	return policy
+}
+
+func parseMode(name string) (mcts.SearchMode, error) {
+	switch name {
+	case "", "determinized":
+		return mcts.Determinized, nil
+	case "ismcts":
+		return mcts.ISMCTS, nil
+	}
+	return mcts.Determinized, fmt.Errorf("unknown search mode %q", name)
+}
+
+// DefaultConfig is the experiment run without a config file
+func DefaultConfig() Config {
+	config := Config{
+		Games:   500,
+		Threads: 10,
+		Output:  "./benchmarks",
+		// The variations below are compared to the agent they vary
+		Baseline: "ScoreSigmoidEval 2",
+		Agents: []AgentConfig{
+			{Name: "Random", Player: "random"},
+		},
+	}
+	budgets := []struct {
+		determinizations, trajectories int
+	}{{1, 1500}, {5, 2500}, {10, 3500}}
+	evals := []struct {
+		name, eval string
+	}{
+		{"LinearEval", "linear"},
+		{"BinaryEval", "binary"},
+		{"ScoreEval", "score"},
+		{"ScoreSigmoidEval", "score-sigmoid"},
+	}
+	for _, eval := range evals {
+		for i, budget := range budgets {
+			config.Agents = append(config.Agents, AgentConfig{
+				Name:             fmt.Sprintf("%s %d", eval.name, i+1),
+				Eval:             eval.eval,
+				Determinizations: budget.determinizations,
+				Trajectories:     budget.trajectories,
+			})
+		}
+	}
+	config.Agents = append(config.Agents,
+		AgentConfig{Name: "LinearEval 500ms", Eval: "linear", Trajectories: 2500, ThinkTime: "500ms"},
+		AgentConfig{Name: "ScoreSigmoidEval 500ms", Trajectories: 2500, ThinkTime: "500ms"},
+	)
+	// Variations of ScoreSigmoidEval 2
+	noWraparound := game.DefaultRules()
+	noWraparound.Wraparound = false
+	recycle := game.DefaultRules()
+	recycle.StockRecycles = 1
+	variations := []AgentConfig{
+		{Name: "tree parallel", TreeParallel: true},
+		{Name: "tree reuse", ReuseTree: true},
+		{Name: "transpositions", Transpositions: 1 << 20},
+		{Name: "UCB1-Tuned", Policy: "ucb1-tuned"},
+		{Name: "PUCT", Policy: "puct"},
+		{Name: "robust child", Final: "visits"},
+		{Name: "secure child", Final: "secure"},
+		{Name: "ISMCTS", Mode: "ismcts"},
+		{Name: "no draw rollout", Rollout: "no-draw"},
+		{Name: "lookahead rollout", Rollout: "lookahead"},
+		{Name: "epsilon-greedy rollout", Rollout: "epsilon-greedy"},
+		{Name: "learned rollout", Rollout: "learned"},
+		{Name: "no wraparound", Rules: &noWraparound},
+		{Name: "one recycle", Rules: &recycle},
+	}
+	for _, variation := range variations {
+		variation.Name = "ScoreSigmoidEval 2 " + variation.Name
+		variation.Determinizations = 5
+		variation.Trajectories = 2500
+		config.Agents = append(config.Agents, variation)
+	}
+	config.Agents = append(config.Agents, AgentConfig{
+		Name:             "BinaryEval 2 Thompson",
+		Eval:             "binary",
+		Determinizations: 5,
+		Trajectories:     2500,
+		Policy:           "thompson",
+	})
+	return config
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/MatiasLyyra/TriPeaks/game"
+	"github.com/MatiasLyyra/TriPeaks/mcts"
+)
+
+// countTraining replaces the training of the learned policy with a counter
+// for the duration of the test
+func countTraining(t *testing.T) *int {
+	trained := 0
+	train := trainLearned
+	trainLearned = func(rules game.Rules) mcts.Learned {
+		trained++
+		return mcts.TrainLearned(1, rules, 1)
+	}
+	learnedRollouts = make(map[game.Rules]mcts.Learned)
+	t.Cleanup(func() {
+		trainLearned = train
+		learnedRollouts = make(map[game.Rules]mcts.Learned)
+	})
+	return &trained
+}
+
+func TestLearnedRolloutIsTrainedOncePerRules(t *testing.T) {
+	trained := countTraining(t)
+	recycles := game.DefaultRules()
+	recycles.StockRecycles = 1
+	config := Config{
+		Games:   1,
+		Workers: 3,
+		Agents: []AgentConfig{
+			{Name: "a", Determinizations: 1, Rollout: "learned"},
+			{Name: "b", Determinizations: 1, Rollout: "learned"},
+			{Name: "c", Determinizations: 1, Rollout: "learned", Rules: &recycles},
+		},
+	}
+	for _, a := range config.Agents {
+		if _, _, err := a.player(config); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if *trained != 2 {
+		t.Fatalf("trained %d times for two rules", *trained)
+	}
+	learnedRollout(nil)
+	if *trained != 2 {
+		t.Fatalf("the default rules were trained again")
+	}
+}
+
+func TestPlayGamesSkipsFinishedAgents(t *testing.T) {
+	trained := countTraining(t)
+	config := Config{
+		Games:   1,
+		Workers: 2,
+		Agents: []AgentConfig{
+			{Name: "random", Player: "random"},
+			{Name: "learned", Determinizations: 1, Rollout: "learned"},
+		},
+	}
+	options := make([]BenchmarkOptions, len(config.Agents))
+	for i, a := range config.Agents {
+		var err error
+		if options[i], _, err = a.options(config); err != nil {
+			t.Fatal(err)
+		}
+	}
+	records := 0
+	for range playGames(config, options, []job{{0, 1}}) {
+		records++
+	}
+	if records != 1 {
+		t.Fatalf("%d records, want 1", records)
+	}
+	if *trained != 0 {
+		t.Fatalf("trained the rollout of an agent with no games to play")
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// histogramBuckets splits the clear rates into tenths, the last bucket
+// holding the games that were won
+const histogramBuckets = 11
+
+func median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[middle-1] + sorted[middle]) / 2
+	}
+	return sorted[middle]
+}
+
+func medianInts(values []int) float64 {
+	return median(intsToFloats(values))
+}
+
+func intsToFloats(values []int) []float64 {
+	floats := make([]float64, len(values))
+	for i, v := range values {
+		floats[i] = float64(v)
+	}
+	return floats
+}
+
+// clearHistogram counts the games in each tenth of the clear rate, the
+// cleared games in their own bucket
+func clearHistogram(rates []float64) []int {
+	counts := make([]int, histogramBuckets)
+	for _, rate := range rates {
+		bucket := int(rate * 10)
+		if rate >= 1 {
+			bucket = 10
+		} else if bucket > 9 {
+			bucket = 9
+		}
+		counts[bucket]++
+	}
+	return counts
+}
+
+// WriteHistogramCsv writes the clear rate histogram of every agent in CSV
+func WriteHistogramCsv(results []BenchmarkResult, w io.Writer) error {
+	writer := csv.NewWriter(w)
+	header := []string{"name"}
+	for bucket := 0; bucket < histogramBuckets-1; bucket++ {
+		header = append(header, fmt.Sprintf("%d-%d%%", bucket*10, bucket*10+9))
+	}
+	writer.Write(append(header, "100%"))
+	for _, r := range results {
+		line := []string{r.Name}
+		for _, count := range clearHistogram(r.ClearRates) {
+			line = append(line, fmt.Sprint(count))
+		}
+		writer.Write(line)
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"sync"
+
+	"github.com/MatiasLyyra/TriPeaks/game"
+	"github.com/MatiasLyyra/TriPeaks/solver"
+)
+
+// oracleMaxNodes limits the perfect information search done for each deal,
+// lowered by the tests
+var oracleMaxNodes = 5000000
+
+// oracleKey identifies a deal and the rules it is solved with
+type oracleKey struct {
+	seed  uint64
+	rules game.Rules
+}
+
+type oracleResult struct {
+	once     sync.Once
+	winnable bool
+	known    bool
+}
+
+// oracle caches the solutions of the deals, every agent is dealt the same
+// games and the workers would otherwise solve each of them again
+var oracle = struct {
+	sync.Mutex
+	deals map[oracleKey]*oracleResult
+}{deals: make(map[oracleKey]*oracleResult)}
+
+// oracleWinnable tells whether the deal of the seed can be won with perfect
+// information. Known is false if the search ran out of nodes before finding
+// a win or ruling one out. The deal is solved once, a worker asking for a
+// deal being solved waits for the result.
+func oracleWinnable(seed uint64, tri *game.TriPeaks) (winnable, known bool) {
+	key := oracleKey{seed, tri.Rules}
+	oracle.Lock()
+	result, found := oracle.deals[key]
+	if !found {
+		result = &oracleResult{}
+		oracle.deals[key] = result
+	}
+	oracle.Unlock()
+	result.once.Do(func() {
+		winnable, complete := solver.Winnable(tri, oracleMaxNodes)
+		result.winnable = winnable
+		result.known = winnable || complete
+	})
+	return result.winnable, result.known
+}
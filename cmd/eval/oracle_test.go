@@ -0,0 +1,52 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/MatiasLyyra/TriPeaks/agent"
+	"github.com/MatiasLyyra/TriPeaks/deck"
+	"github.com/MatiasLyyra/TriPeaks/game"
+)
+
+// limitOracle solves the deals with at most maxNodes nodes and an empty cache
+// for the duration of the test
+func limitOracle(t *testing.T, maxNodes int) {
+	limit := oracleMaxNodes
+	oracleMaxNodes = maxNodes
+	oracle.deals = make(map[oracleKey]*oracleResult)
+	t.Cleanup(func() {
+		oracleMaxNodes = limit
+		oracle.deals = make(map[oracleKey]*oracleResult)
+	})
+}
+
+func dealSeed(seed uint64) *game.TriPeaks {
+	stock := deck.New()
+	stock.ShuffleSeed(seed)
+	return game.NewTripeaks(*stock, game.DefaultRules())
+}
+
+func TestOracleUnknownWhenOutOfNodes(t *testing.T) {
+	limitOracle(t, 1)
+	winnable, known := oracleWinnable(1, dealSeed(1))
+	if winnable || known {
+		t.Fatalf("a search of one node gave winnable %v and known %v", winnable, known)
+	}
+	record := playGame(BenchmarkOptions{Name: "random"}, &agent.Random{}, 1)
+	if record.OracleWinnable || !record.OracleUnknown {
+		t.Fatalf("the record has winnable %v and unknown %v", record.OracleWinnable, record.OracleUnknown)
+	}
+	result := aggregate(BenchmarkOptions{Name: "random"}, []uint64{1}, map[recordKey]GameRecord{{"random", 1}: record})
+	if result.OracleWins != 0 || result.OracleUnknown != 1 {
+		t.Fatalf("%d oracle wins and %d unknown, want 0 and 1", result.OracleWins, result.OracleUnknown)
+	}
+}
+
+func TestOracleKnownWhenSolved(t *testing.T) {
+	limitOracle(t, oracleMaxNodes)
+	for seed := uint64(1); seed <= 3; seed++ {
+		if _, known := oracleWinnable(seed, dealSeed(seed)); !known {
+			t.Fatalf("seed %d is unknown", seed)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+)
+
+// z95 is the quantile of the normal distribution for 95 % intervals
+const z95 = 1.959964
+
+// bootstrapSamples is the number of resamples of the bootstrap intervals
+const bootstrapSamples = 10000
+
+// Comparison is the paired comparison of an agent against the baseline on
+// the same deals
+type Comparison struct {
+	Agent    string
+	Baseline string
+	// N is the number of deals played by both
+	N int
+	// Skipped counts the deals played by only one of them, they are left out
+	// of the comparison
+	Skipped int
+	// WinRate of the agent with its 95 % Wilson score interval
+	WinRate     float64
+	WinRateLow  float64
+	WinRateHigh float64
+	// BaselineWinRate of the baseline with its Wilson score interval
+	BaselineWinRate     float64
+	BaselineWinRateLow  float64
+	BaselineWinRateHigh float64
+	// Difference is the win rate of the agent minus that of the baseline
+	// with its 95 % paired bootstrap interval
+	Difference     float64
+	DifferenceLow  float64
+	DifferenceHigh float64
+	// OnlyAgent and OnlyBaseline count the deals won by just one of them
+	OnlyAgent    int
+	OnlyBaseline int
+	// PValue is the two-sided exact McNemar test, the sign test of the deals
+	// won by just one of them
+	PValue float64
+}
+
+// comparePaired compares every result against the baseline on the deals
+// both of them played
+func comparePaired(results []BenchmarkResult, baseline BenchmarkResult, seed int64) []Comparison {
+	random := rand.New(rand.NewSource(seed))
+	comparisons := make([]Comparison, 0, len(results))
+	for _, r := range results {
+		if r.Name == baseline.Name {
+			continue
+		}
+		comparisons = append(comparisons, compare(r, baseline, random))
+	}
+	return comparisons
+}
+
+func compare(r, baseline BenchmarkResult, random *rand.Rand) Comparison {
+	won, baselineWon, skipped := pairBySeed(r, baseline)
+	n := len(won)
+	c := Comparison{
+		Agent:    r.Name,
+		Baseline: baseline.Name,
+		N:        n,
+		Skipped:  skipped,
+	}
+	wins, baselineWins := 0, 0
+	for i := range won {
+		if won[i] {
+			wins++
+		}
+		if baselineWon[i] {
+			baselineWins++
+		}
+		if won[i] && !baselineWon[i] {
+			c.OnlyAgent++
+		} else if !won[i] && baselineWon[i] {
+			c.OnlyBaseline++
+		}
+	}
+	if n == 0 {
+		c.PValue = 1
+		return c
+	}
+	c.WinRate = float64(wins) / float64(n)
+	c.WinRateLow, c.WinRateHigh = wilson(wins, n, z95)
+	c.BaselineWinRate = float64(baselineWins) / float64(n)
+	c.BaselineWinRateLow, c.BaselineWinRateHigh = wilson(baselineWins, n, z95)
+	c.Difference = float64(c.OnlyAgent-c.OnlyBaseline) / float64(n)
+	c.DifferenceLow, c.DifferenceHigh = bootstrapDifference(won, baselineWon, random)
+	c.PValue = signTest(c.OnlyAgent, c.OnlyBaseline)
+	return c
+}
+
+// pairBySeed returns the outcomes of the deals played by both in the order
+// of the agent and the number of deals played by only one of them
+func pairBySeed(r, baseline BenchmarkResult) ([]bool, []bool, int) {
+	baselineWon := make(map[uint64]bool, len(baseline.Seeds))
+	for i, seed := range baseline.Seeds {
+		baselineWon[seed] = baseline.Won[i]
+	}
+	var won, paired []bool
+	for i, seed := range r.Seeds {
+		if b, found := baselineWon[seed]; found {
+			won = append(won, r.Won[i])
+			paired = append(paired, b)
+		}
+	}
+	skipped := len(r.Seeds) + len(baseline.Seeds) - 2*len(won)
+	return won, paired, skipped
+}
+
+// wilson returns the Wilson score interval of a binomial proportion
+func wilson(successes, n int, z float64) (float64, float64) {
+	p := float64(successes) / float64(n)
+	nf := float64(n)
+	denominator := 1 + z*z/nf
+	center := (p + z*z/(2*nf)) / denominator
+	margin := z * math.Sqrt(p*(1-p)/nf+z*z/(4*nf*nf)) / denominator
+	return math.Max(0, center-margin), math.Min(1, center+margin)
+}
+
+// bootstrapDifference returns the 95 % percentile interval of the
+// difference of the win rates, resampling the deals with their pairs
+func bootstrapDifference(won, baselineWon []bool, random *rand.Rand) (float64, float64) {
+	n := len(won)
+	differences := make([]float64, bootstrapSamples)
+	for i := range differences {
+		d := 0
+		for j := 0; j < n; j++ {
+			k := random.Intn(n)
+			if won[k] {
+				d++
+			}
+			if baselineWon[k] {
+				d--
+			}
+		}
+		differences[i] = float64(d) / float64(n)
+	}
+	sort.Float64s(differences)
+	return differences[int(0.025*bootstrapSamples)], differences[int(0.975*bootstrapSamples)-1]
+}
+
+// signTest returns the two-sided p-value of the exact binomial test of a
+// and b successes out of a + b trials with probability 1/2
+func signTest(a, b int) float64 {
+	n := a + b
+	if n == 0 {
+		return 1
+	}
+	k := a
+	if b < k {
+		k = b
+	}
+	// P(X <= k) for X ~ Bin(n, 1/2)
+	tail := 0.0
+	for i := 0; i <= k; i++ {
+		tail += math.Exp(logChoose(n, i) - float64(n)*math.Ln2)
+	}
+	return math.Min(1, 2*tail)
+}
+
+func logChoose(n, k int) float64 {
+	a, _ := math.Lgamma(float64(n + 1))
+	b, _ := math.Lgamma(float64(k + 1))
+	c, _ := math.Lgamma(float64(n - k + 1))
+	return a - b - c
+}
+
+// WritePairedCsv writes the comparisons in CSV
+func WritePairedCsv(comparisons []Comparison, w io.Writer) error {
+	writer := csv.NewWriter(w)
+	writer.Write(strings.Split("agent,baseline,n,win_rate,win_rate_low,win_rate_high,"+
+		"baseline_win_rate,baseline_win_rate_low,baseline_win_rate_high,"+
+		"difference,difference_low,difference_high,only_agent,only_baseline,p_value,skipped", ","))
+	for _, c := range comparisons {
+		writer.Write(append([]string{c.Agent, c.Baseline}, formatFields("%d,%.4f,%.4f,%.4f,%.4f,%.4f,%.4f,%.4f,%.4f,%.4f,%d,%d,%.4g,%d",
+			c.N, c.WinRate, c.WinRateLow, c.WinRateHigh,
+			c.BaselineWinRate, c.BaselineWinRateLow, c.BaselineWinRateHigh,
+			c.Difference, c.DifferenceLow, c.DifferenceHigh, c.OnlyAgent, c.OnlyBaseline, c.PValue, c.Skipped)...))
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// printComparisons prints the comparisons as a table
+func printComparisons(comparisons []Comparison, w io.Writer) {
+	for _, c := range comparisons {
+		fmt.Fprintf(w, "%s vs %s: %.1f %% [%.1f, %.1f] vs %.1f %% [%.1f, %.1f], difference %+.1f %% [%+.1f, %+.1f], %d-%d, p = %.4g\n",
+			c.Agent, c.Baseline,
+			100*c.WinRate, 100*c.WinRateLow, 100*c.WinRateHigh,
+			100*c.BaselineWinRate, 100*c.BaselineWinRateLow, 100*c.BaselineWinRateHigh,
+			100*c.Difference, 100*c.DifferenceLow, 100*c.DifferenceHigh,
+			c.OnlyAgent, c.OnlyBaseline, c.PValue)
+		if c.Skipped > 0 {
+			fmt.Fprintf(w, "  %d deals played by only one of them were skipped\n", c.Skipped)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-4
+}
+
+func TestSignTest(t *testing.T) {
+	tests := []struct {
+		a, b int
+		want float64
+	}{
+		{0, 0, 1},
+		{5, 5, 1},
+		{3, 2, 1},
+		// Everything on one side: 2 / 2^n
+		{0, 5, 0.0625},
+		{10, 0, 0.001953125},
+		// 2 * (1 + 10) / 2^10
+		{1, 9, 0.021484375},
+		{9, 1, 0.021484375},
+	}
+	for _, test := range tests {
+		if got := signTest(test.a, test.b); !near(got, test.want) {
+			t.Errorf("signTest(%d, %d) = %f, want %f", test.a, test.b, got, test.want)
+		}
+	}
+}
+
+func TestWilson(t *testing.T) {
+	tests := []struct {
+		successes, n int
+		low, high    float64
+	}{
+		{0, 10, 0, 0.2775},
+		{5, 10, 0.2366, 0.7634},
+		{10, 10, 0.7225, 1},
+	}
+	for _, test := range tests {
+		low, high := wilson(test.successes, test.n, z95)
+		if !near(low, test.low) || !near(high, test.high) {
+			t.Errorf("wilson(%d, %d) = [%f, %f], want [%f, %f]", test.successes, test.n, low, high, test.low, test.high)
+		}
+	}
+}
+
+// pairedResult returns a result of the deals 1 to n won as given
+func pairedResult(name string, won ...bool) BenchmarkResult {
+	r := BenchmarkResult{Name: name, Won: won}
+	for i := range won {
+		r.Seeds = append(r.Seeds, uint64(i+1))
+	}
+	return r
+}
+
+func TestCompareAllTies(t *testing.T) {
+	won := []bool{true, false, true, true, false, false}
+	c := compare(pairedResult("agent", won...), pairedResult("base", won...), rand.New(rand.NewSource(1)))
+	if c.N != 6 || c.OnlyAgent != 0 || c.OnlyBaseline != 0 || c.Skipped != 0 {
+		t.Fatalf("compared %+v", c)
+	}
+	if c.PValue != 1 || c.Difference != 0 || c.DifferenceLow != 0 || c.DifferenceHigh != 0 {
+		t.Fatalf("ties gave p %f and difference %f [%f, %f]", c.PValue, c.Difference, c.DifferenceLow, c.DifferenceHigh)
+	}
+	if c.WinRate != 0.5 || c.WinRate != c.BaselineWinRate {
+		t.Fatalf("win rates %f and %f, want 0.5", c.WinRate, c.BaselineWinRate)
+	}
+}
+
+func TestCompareOneSided(t *testing.T) {
+	agent := pairedResult("agent", true, true, true, true, true, true, true, true, false, false)
+	base := pairedResult("base", false, false, false, false, false, false, false, false, false, false, true)
+	c := compare(agent, base, rand.New(rand.NewSource(1)))
+	// The eleventh deal was only played by the baseline
+	if c.N != 10 || c.Skipped != 1 || c.OnlyAgent != 8 || c.OnlyBaseline != 0 {
+		t.Fatalf("compared %+v", c)
+	}
+	if !near(c.PValue, 2/256.0) || !near(c.Difference, 0.8) {
+		t.Fatalf("p %f and difference %f, want %f and 0.8", c.PValue, c.Difference, 2/256.0)
+	}
+}
+
+func TestBootstrapIsSeeded(t *testing.T) {
+	won := []bool{true, true, false, true, false, true, true, false, true, true}
+	baseline := []bool{false, true, false, false, false, true, false, false, true, false}
+	low, high := bootstrapDifference(won, baseline, rand.New(rand.NewSource(7)))
+	if !near(low, 0.1) || !near(high, 0.7) {
+		t.Fatalf("interval [%f, %f], want [0.1, 0.7]", low, high)
+	}
+	againLow, againHigh := bootstrapDifference(won, baseline, rand.New(rand.NewSource(7)))
+	if againLow != low || againHigh != high {
+		t.Fatalf("seeded intervals differ: [%f, %f] and [%f, %f]", low, high, againLow, againHigh)
+	}
+}
+
+// readCsv parses the output and checks that every row has the fields of the
+// header
+func readCsv(t *testing.T, data []byte) [][]string {
+	t.Helper()
+	reader := csv.NewReader(bytes.NewReader(data))
+	rows, err := reader.ReadAll()
+	if err != nil {
+		t.Fatalf("invalid CSV: %s\n%s", err, data)
+	}
+	return rows
+}
+
+func TestCsvEscapesNames(t *testing.T) {
+	name := `UCB1 "tuned", C=1`
+	result := pairedResult(name, true, false)
+	result.ClearRates = []float64{1, 0.5}
+	var buf bytes.Buffer
+	WriteCsv([]BenchmarkResult{result}, &buf)
+	if rows := readCsv(t, buf.Bytes()); len(rows) != 2 || rows[1][0] != name {
+		t.Fatalf("results rows %q", rows)
+	}
+	buf.Reset()
+	if err := WritePairedCsv([]Comparison{{Agent: name, Baseline: "a,b"}}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if rows := readCsv(t, buf.Bytes()); len(rows) != 2 || rows[1][0] != name || rows[1][1] != "a,b" {
+		t.Fatalf("paired rows %q", rows)
+	}
+	buf.Reset()
+	if err := WriteHistogramCsv([]BenchmarkResult{result}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if rows := readCsv(t, buf.Bytes()); len(rows) != 2 || rows[1][0] != name {
+		t.Fatalf("histogram rows %q", rows)
+	}
+}
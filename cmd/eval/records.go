@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"github.com/MatiasLyyra/TriPeaks/game"
+	"github.com/MatiasLyyra/TriPeaks/mcts"
+)
+
+// GameRecord is a single game played by an agent, one line of the game log.
+// The game can be replayed from the deal and the moves.
+type GameRecord struct {
+	Agent string `json:"agent"`
+	Seed  uint64 `json:"seed"`
+	// Deal is the code of the deck, see deck.Deck.Code
+	Deal         string `json:"deal"`
+	Won          bool   `json:"won"`
+	Score        int    `json:"score"`
+	CardsCleared int    `json:"cardsCleared"`
+	// Cards is the number of cards on the tableau at the start
+	Cards int `json:"cards"`
+	Draws int `json:"draws"`
+	// LongestStreak is the most cards played in a row without drawing
+	LongestStreak int `json:"longestStreak"`
+	PeaksCleared  int `json:"peaksCleared"`
+	// OracleWinnable tells whether the deal can be won with perfect
+	// information. OracleUnknown is set instead if the solver gave up.
+	OracleWinnable bool         `json:"oracleWinnable"`
+	OracleUnknown  bool         `json:"oracleUnknown,omitempty"`
+	Moves          []MoveRecord `json:"moves"`
+	Search         SearchTotals `json:"search"`
+}
+
+// MoveRecord is a move of a game and the time the agent took to choose it
+type MoveRecord struct {
+	// Move is in the format of game.Move.String
+	Move string        `json:"move"`
+	Time time.Duration `json:"time"`
+}
+
+// moveTime returns the total time the agent took to choose the moves
+func (r GameRecord) moveTime() time.Duration {
+	var total time.Duration
+	for _, move := range r.Moves {
+		total += move.Time
+	}
+	return total
+}
+
+// SearchTotals are the statistics of the searches summed over every move
+// searched. The durations are in nanoseconds.
+type SearchTotals struct {
+	Searches        int           `json:"searches"`
+	Iterations      int           `json:"iterations"`
+	Nodes           int           `json:"nodes"`
+	MaxDepth        int           `json:"maxDepth"`
+	SearchTime      time.Duration `json:"searchTime"`
+	Selection       time.Duration `json:"selection"`
+	Expansion       time.Duration `json:"expansion"`
+	Simulation      time.Duration `json:"simulation"`
+	Backpropagation time.Duration `json:"backpropagation"`
+	// Depths and Rollouts sum the averages of the searches weighted by their
+	// iterations
+	Depths   float64 `json:"depths"`
+	Rollouts float64 `json:"rollouts"`
+	// Spreads sums the spread of the scores of the moves played
+	Spreads float64 `json:"spreads"`
+}
+
+// addStats adds the statistics of the search of the move played
+func (t *SearchTotals) addStats(stats mcts.SearchStats, move game.Move) {
+	t.Searches++
+	t.Iterations += stats.Iterations
+	t.Nodes += stats.Nodes
+	if stats.MaxDepth > t.MaxDepth {
+		t.MaxDepth = stats.MaxDepth
+	}
+	t.SearchTime += stats.WallTime
+	t.Selection += stats.Selection
+	t.Expansion += stats.Expansion
+	t.Simulation += stats.Simulation
+	t.Backpropagation += stats.Backpropagation
+	t.Depths += stats.AverageDepth * float64(stats.Iterations)
+	t.Rollouts += stats.AverageRollout * float64(stats.Iterations)
+	pos := move.Pos
+	if move.Kind == game.MoveDraw {
+		pos = -1
+	}
+	t.Spreads += stats.Spread[pos]
+}
+
+func (t *SearchTotals) add(other SearchTotals) {
+	t.Searches += other.Searches
+	t.Iterations += other.Iterations
+	t.Nodes += other.Nodes
+	if other.MaxDepth > t.MaxDepth {
+		t.MaxDepth = other.MaxDepth
+	}
+	t.SearchTime += other.SearchTime
+	t.Selection += other.Selection
+	t.Expansion += other.Expansion
+	t.Simulation += other.Simulation
+	t.Backpropagation += other.Backpropagation
+	t.Depths += other.Depths
+	t.Rollouts += other.Rollouts
+	t.Spreads += other.Spreads
+}
+
+// averages returns the average depth, rollout length and spread of the
+// searches
+func (t SearchTotals) averages() (depth, rollout, spread float64) {
+	if t.Iterations > 0 {
+		depth = t.Depths / float64(t.Iterations)
+		rollout = t.Rollouts / float64(t.Iterations)
+	}
+	if t.Searches > 0 {
+		spread = t.Spreads / float64(t.Searches)
+	}
+	return depth, rollout, spread
+}
+
+// recordKey identifies the game of an agent
+type recordKey struct {
+	agent string
+	seed  uint64
+}
+
+// readLog reads the records of a game log. A last line cut short by an
+// interrupted run is skipped.
+func readLog(path string) ([]GameRecord, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var records []GameRecord
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var record GameRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			log.Printf("%s:%d: skipping invalid record: %s", path, line, err)
+			continue
+		}
+		records = append(records, record)
+	}
+	return records, scanner.Err()
+}
+
+// gameLog appends the records to a JSONL file as the games finish
+type gameLog struct {
+	f       *os.File
+	encoder *json.Encoder
+}
+
+func openLog(path string) (*gameLog, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0666)
+	if err != nil {
+		return nil, err
+	}
+	// A record cut short must not be joined with the next one
+	if info, err := f.Stat(); err == nil && info.Size() > 0 {
+		last := make([]byte, 1)
+		if _, err := f.ReadAt(last, info.Size()-1); err != nil && err != io.EOF {
+			f.Close()
+			return nil, err
+		}
+		if last[0] != '\n' {
+			if _, err := f.Write([]byte("\n")); err != nil {
+				f.Close()
+				return nil, err
+			}
+		}
+	}
+	return &gameLog{f: f, encoder: json.NewEncoder(f)}, nil
+}
+
+func (l *gameLog) write(record GameRecord) error {
+	return l.encoder.Encode(record)
+}
+
+func (l *gameLog) Close() error {
+	return l.f.Close()
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadLogSkipsTruncatedLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), gamesFile)
+	data := `{"agent":"a","seed":1,"score":5}
+{"agent":"a","seed":2,"score":7}
+{"agent":"a","seed":3,"sco`
+	if err := os.WriteFile(path, []byte(data), 0666); err != nil {
+		t.Fatal(err)
+	}
+	records, err := readLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || records[0].Seed != 1 || records[1].Seed != 2 || records[1].Score != 7 {
+		t.Fatalf("read %+v", records)
+	}
+	// The next record starts on a line of its own
+	gameLog, err := openLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := gameLog.write(GameRecord{Agent: "a", Seed: 3}); err != nil {
+		t.Fatal(err)
+	}
+	gameLog.Close()
+	if records, err = readLog(path); err != nil || len(records) != 3 || records[2].Seed != 3 {
+		t.Fatalf("read %+v, %v after appending", records, err)
+	}
+}
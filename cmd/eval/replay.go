@@ -0,0 +1,131 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/MatiasLyyra/TriPeaks/deck"
+	"github.com/MatiasLyyra/TriPeaks/game"
+)
+
+// replayMain replays a game of the game log of a run
+func replayMain(args []string) {
+	flags := flag.NewFlagSet("replay", flag.ExitOnError)
+	run := flags.String("run", "", "run directory of the benchmark")
+	agentName := flags.String("agent", "", "agent that played the game")
+	seed := flags.Uint64("seed", 0, "seed of the deal of the game")
+	outliers := flags.Int("outliers", 0, "list this many of the lowest and highest scoring games of the agent instead")
+	quiet := flags.Bool("quiet", false, "only check that the game replays to the recorded result")
+	flags.Parse(args)
+	if *run == "" || *agentName == "" {
+		log.Fatal("replay needs -run and -agent")
+	}
+
+	config, err := LoadConfig(filepath.Join(*run, experimentFile))
+	if err != nil {
+		log.Fatalf("failed to load the experiment: %s", err)
+	}
+	rules := game.DefaultRules()
+	found := false
+	for _, a := range config.Agents {
+		if a.Name == *agentName {
+			found = true
+			if a.Rules != nil {
+				rules = *a.Rules
+			}
+		}
+	}
+	if !found {
+		log.Fatalf("unknown agent %q", *agentName)
+	}
+	logPath := filepath.Join(*run, gamesFile)
+	all, err := readLog(logPath)
+	if err != nil {
+		log.Fatalf("failed to read %s: %s", logPath, err)
+	}
+	var records []GameRecord
+	for _, record := range all {
+		if record.Agent == *agentName {
+			records = append(records, record)
+		}
+	}
+
+	if *outliers > 0 {
+		printOutliers(records, *outliers, os.Stdout)
+		return
+	}
+	for _, record := range records {
+		if record.Seed != *seed {
+			continue
+		}
+		out := io.Writer(os.Stdout)
+		if *quiet {
+			out = io.Discard
+		}
+		if err := replay(record, rules, out); err != nil {
+			log.Fatalf("replay failed: %s", err)
+		}
+		fmt.Printf("%s replayed seed %d to score %d with %d cards cleared\n", record.Agent, record.Seed, record.Score, record.CardsCleared)
+		return
+	}
+	log.Fatalf("%s did not play seed %d", *agentName, *seed)
+}
+
+// replay plays the moves of the record on its deal, printing the game after
+// every move, and checks that the game ends like it was recorded
+func replay(record GameRecord, rules game.Rules, out io.Writer) error {
+	stock, err := deck.FromCode(record.Deal)
+	if err != nil {
+		return err
+	}
+	tri := game.NewTripeaks(*stock, rules)
+	fmt.Fprintf(out, "Deal: %s\n", record.Deal)
+	for i, moveRecord := range record.Moves {
+		fmt.Fprintf(out, "%s", tri)
+		fmt.Fprintf(out, "Cards in deck: %d\tScore: %d\t\tDiscard: %s\n", tri.Stock.Len(), tri.Score, tri.Discard())
+		move, err := game.ParseMove(moveRecord.Move)
+		if err != nil {
+			return err
+		}
+		if move.Kind == game.MoveSelect && move.Pos < len(tri.Cards) {
+			fmt.Fprintf(out, "Move %d: discard %s on position %d (%s)\n", i+1, tri.Cards[move.Pos], move.Pos, moveRecord.Time)
+		} else {
+			fmt.Fprintf(out, "Move %d: %s (%s)\n", i+1, move, moveRecord.Time)
+		}
+		if !tri.Play(move) {
+			return fmt.Errorf("move %d %s is illegal", i+1, move)
+		}
+	}
+	fmt.Fprintf(out, "%s", tri)
+	fmt.Fprintf(out, "Final score: %d\n", tri.Score)
+	if tri.Score != record.Score || len(tri.Cards)-tri.CardsLeft != record.CardsCleared {
+		return fmt.Errorf("replayed to score %d with %d cards cleared, recorded %d with %d",
+			tri.Score, len(tri.Cards)-tri.CardsLeft, record.Score, record.CardsCleared)
+	}
+	return nil
+}
+
+// printOutliers lists the lowest and highest scoring games
+func printOutliers(records []GameRecord, n int, out io.Writer) {
+	sorted := append([]GameRecord(nil), records...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Score < sorted[j].Score
+	})
+	if 2*n > len(sorted) {
+		n = (len(sorted) + 1) / 2
+	}
+	fmt.Fprintln(out, "Lowest scores:")
+	for _, r := range sorted[:n] {
+		fmt.Fprintf(out, "  seed %d score %d cleared %d/%d draws %d\n", r.Seed, r.Score, r.CardsCleared, r.Cards, r.Draws)
+	}
+	fmt.Fprintln(out, "Highest scores:")
+	for i := len(sorted) - 1; i >= len(sorted)-n; i-- {
+		r := sorted[i]
+		fmt.Fprintf(out, "  seed %d score %d cleared %d/%d draws %d\n", r.Seed, r.Score, r.CardsCleared, r.Cards, r.Draws)
+	}
+}
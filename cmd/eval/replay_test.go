@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/MatiasLyyra/TriPeaks/agent"
+	"github.com/MatiasLyyra/TriPeaks/game"
+)
+
+func TestReplayReproducesLoggedGames(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), gamesFile)
+	gameLog, err := openLog(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recycling := game.DefaultRules()
+	recycling.StockRecycles = 1
+	for _, rules := range []game.Rules{game.DefaultRules(), recycling} {
+		for seed := uint64(1); seed <= 3; seed++ {
+			options := BenchmarkOptions{Name: "random", Rules: &rules}
+			if err := gameLog.write(playGame(options, agent.NewRandom(int64(seed)), seed)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	gameLog.Close()
+	records, err := readLog(logPath)
+	if err != nil || len(records) != 6 {
+		t.Fatalf("read %d games, %v", len(records), err)
+	}
+	for i, record := range records {
+		rules := game.DefaultRules()
+		if i >= 3 {
+			rules = recycling
+		}
+		var out bytes.Buffer
+		if err := replay(record, rules, &out); err != nil {
+			t.Fatalf("seed %d of game %d: %s", record.Seed, i, err)
+		}
+		if moves := strings.Count(out.String(), "\nMove "); moves != len(record.Moves) {
+			t.Fatalf("seed %d of game %d: printed %d of %d moves", record.Seed, i, moves, len(record.Moves))
+		}
+		if !strings.Contains(out.String(), "Deal: "+record.Deal) {
+			t.Fatalf("seed %d of game %d: the deal was not printed", record.Seed, i)
+		}
+	}
+}
+
+func TestReplayDetectsChangedRecords(t *testing.T) {
+	rules := game.DefaultRules()
+	record := playGame(BenchmarkOptions{Name: "random"}, agent.NewRandom(1), 1)
+
+	scored := record
+	scored.Score++
+	if err := replay(scored, rules, io.Discard); err == nil {
+		t.Fatalf("a changed score replayed")
+	}
+	cleared := record
+	cleared.CardsCleared++
+	if err := replay(cleared, rules, io.Discard); err == nil {
+		t.Fatalf("a changed number of cards cleared replayed")
+	}
+	// Drawing more than the stock holds cannot be legal
+	illegal := record
+	illegal.Moves = make([]MoveRecord, 60)
+	for i := range illegal.Moves {
+		illegal.Moves[i].Move = "draw"
+	}
+	if err := replay(illegal, rules, io.Discard); err == nil || !strings.Contains(err.Error(), "illegal") {
+		t.Fatalf("replaying illegal moves gave %v", err)
+	}
+	invalid := record
+	invalid.Moves = []MoveRecord{{Move: "jump"}}
+	if err := replay(invalid, rules, io.Discard); err == nil {
+		t.Fatalf("an invalid move replayed")
+	}
+}
@@ -0,0 +1,564 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// reportAgent is the result of an agent of a run with its intervals
+type reportAgent struct {
+	BenchmarkResult
+	Eval   string
+	Budget string
+	// WinRateLow and WinRateHigh are the 95 % Wilson score interval
+	WinRateLow  float64
+	WinRateHigh float64
+	// MeanScore with its 95 % normal interval
+	MeanScore     float64
+	MeanScoreLow  float64
+	MeanScoreHigh float64
+	// Iterations is the average number of iterations searched per move, 0
+	// for the agents that do not search
+	Iterations float64
+	games      map[uint64]GameRecord
+}
+
+func (a reportAgent) WinRate() float64 {
+	if a.N == 0 {
+		return 0
+	}
+	return float64(a.GamesWon) / float64(a.N)
+}
+
+// reportRun is a run directory read for the report
+type reportRun struct {
+	Dir    string
+	Config Config
+	Agents []reportAgent
+}
+
+// regression is the difference of an agent of a run to the same agent of
+// the baseline run
+type regression struct {
+	Run      string
+	Agent    string
+	Current  reportAgent
+	Baseline reportAgent
+	// Paired is the number of deals played in both runs, the differences are
+	// computed on them if not 0
+	Paired int
+	// WinRate and MeanScore differences with their 95 % intervals
+	WinRate       float64
+	WinRateLow    float64
+	WinRateHigh   float64
+	MeanScore     float64
+	MeanScoreLow  float64
+	MeanScoreHigh float64
+	// PValue is the exact McNemar test of the paired deals, NaN if unpaired
+	PValue    float64
+	Regressed []string
+}
+
+// thresholds are the largest drops allowed before the report fails
+type thresholds struct {
+	// WinRate is in percentage points, disabled if negative
+	WinRate float64
+	// MeanScore is in points, disabled if negative
+	MeanScore float64
+	// Confident compares the upper end of the interval of the difference
+	// instead of the difference
+	Confident bool
+}
+
+// reportMain writes a report of one or more runs
+func reportMain(args []string) {
+	flags := flag.NewFlagSet("report", flag.ExitOnError)
+	format := flags.String("format", "markdown", "format of the report, markdown or html")
+	output := flags.String("out", "", "file the report is written to, stdout if empty")
+	baselineDir := flags.String("baseline", "", "run directory the agents are compared to")
+	maxWinDrop := flags.Float64("max-win-drop", -1, "fail if the win rate of an agent drops more than this many percentage points from the baseline run, disabled if negative")
+	maxScoreDrop := flags.Float64("max-score-drop", -1, "fail if the mean score of an agent drops more than this many points from the baseline run, disabled if negative")
+	confident := flags.Bool("confident", false, "fail only if the whole 95 % interval of the drop is over the threshold")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s report [flags] run...\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	if *format != "markdown" && *format != "html" {
+		log.Fatalf("unknown format %q", *format)
+	}
+
+	runs := make([]reportRun, flags.NArg())
+	for i, path := range flags.Args() {
+		var err error
+		if runs[i], err = loadRun(path); err != nil {
+			log.Fatalf("failed to read run %s: %s", path, err)
+		}
+	}
+	var regressions []regression
+	var baseline *reportRun
+	if *baselineDir != "" {
+		run, err := loadRun(*baselineDir)
+		if err != nil {
+			log.Fatalf("failed to read baseline run %s: %s", *baselineDir, err)
+		}
+		baseline = &run
+		limits := thresholds{WinRate: *maxWinDrop, MeanScore: *maxScoreDrop, Confident: *confident}
+		for _, run := range runs {
+			if run.Dir == baseline.Dir {
+				continue
+			}
+			regressions = append(regressions, compareRuns(run, *baseline, limits)...)
+		}
+	}
+
+	out := os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			log.Fatalf("failed to create %s: %s", *output, err)
+		}
+		out = f
+	}
+	charts := reportCharts(runs)
+	var err error
+	if *format == "html" {
+		err = writeHTMLReport(out, runs, baseline, regressions, charts)
+	} else {
+		err = writeMarkdownReport(out, runs, baseline, regressions, charts)
+	}
+	if out != os.Stdout {
+		if closeErr := out.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		log.Fatalf("failed to write the report: %s", err)
+	}
+
+	failed := 0
+	for _, r := range regressions {
+		if len(r.Regressed) > 0 {
+			log.Printf("%s regressed in %s: %s", r.Agent, r.Run, strings.Join(r.Regressed, ", "))
+			failed++
+		}
+	}
+	if failed > 0 {
+		log.Printf("%d agents regressed", failed)
+		os.Exit(1)
+	}
+}
+
+// loadRun reads the experiment and the game log of a run directory. The path
+// can also be the game log itself.
+func loadRun(path string) (reportRun, error) {
+	dir := filepath.Clean(path)
+	if info, err := os.Stat(dir); err == nil && !info.IsDir() {
+		dir = filepath.Dir(dir)
+	}
+	run := reportRun{Dir: dir}
+	var err error
+	if run.Config, err = LoadConfig(filepath.Join(dir, experimentFile)); err != nil {
+		return run, err
+	}
+	all, err := readLog(filepath.Join(dir, gamesFile))
+	if err != nil {
+		return run, err
+	}
+	records := make(map[recordKey]GameRecord)
+	for _, record := range all {
+		records[recordKey{record.Agent, record.Seed}] = record
+	}
+	seeds := run.Config.DealSeeds()
+	for _, a := range run.Config.Agents {
+		result := aggregate(BenchmarkOptions{Name: a.Name}, seeds, records)
+		if result.N == 0 {
+			continue
+		}
+		agent := reportAgent{
+			BenchmarkResult: result,
+			Eval:            agentEval(a),
+			Budget:          agentBudget(a, run.Config),
+			games:           make(map[uint64]GameRecord),
+		}
+		for _, seed := range seeds {
+			if record, found := records[recordKey{a.Name, seed}]; found {
+				agent.games[seed] = record
+			}
+		}
+		agent.WinRateLow, agent.WinRateHigh = wilson(result.GamesWon, result.N, z95)
+		agent.MeanScore, agent.MeanScoreLow, agent.MeanScoreHigh = meanInterval(intsToFloats(result.Scores))
+		if result.Searches > 0 {
+			agent.Iterations = float64(result.Iterations) / float64(result.Searches)
+		}
+		run.Agents = append(run.Agents, agent)
+	}
+	return run, nil
+}
+
+func agentEval(a AgentConfig) string {
+	switch {
+	case a.Player == "random":
+		return "random"
+	case a.Eval == "":
+		return "score-sigmoid"
+	}
+	return a.Eval
+}
+
+// agentBudget describes the compute the agent was given for every move
+func agentBudget(a AgentConfig, c Config) string {
+	if a.Player == "random" {
+		return "-"
+	}
+	threads := a.Threads
+	if threads == 0 {
+		threads = c.Threads
+	}
+	if a.ThinkTime != "" {
+		return fmt.Sprintf("%s × %d threads", a.ThinkTime, threads)
+	}
+	return fmt.Sprintf("%d × %d det × %d traj", threads, a.Determinizations, a.Trajectories)
+}
+
+// meanInterval returns the mean and its 95 % normal interval
+func meanInterval(values []float64) (float64, float64, float64) {
+	n := float64(len(values))
+	if n == 0 {
+		return 0, 0, 0
+	}
+	mean, variance := meanVariance(values)
+	margin := z95 * math.Sqrt(variance/n)
+	return mean, mean - margin, mean + margin
+}
+
+// meanVariance returns the mean and the sample variance
+func meanVariance(values []float64) (float64, float64) {
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	mean := sum / float64(len(values))
+	if len(values) < 2 {
+		return mean, 0
+	}
+	squares := 0.0
+	for _, v := range values {
+		squares += (v - mean) * (v - mean)
+	}
+	return mean, squares / float64(len(values)-1)
+}
+
+// compareRuns compares the agents of the run to the agents of the same name
+// in the baseline run. The deals played in both are compared pairwise, the
+// whole runs otherwise.
+func compareRuns(run, baseline reportRun, limits thresholds) []regression {
+	random := rand.New(rand.NewSource(run.Config.Seed))
+	var regressions []regression
+	for _, current := range run.Agents {
+		var base reportAgent
+		found := false
+		for _, a := range baseline.Agents {
+			if a.Name == current.Name {
+				base, found = a, true
+			}
+		}
+		if !found {
+			continue
+		}
+		r := regression{
+			Run:      run.Dir,
+			Agent:    current.Name,
+			Current:  current,
+			Baseline: base,
+			PValue:   math.NaN(),
+		}
+		var seeds []uint64
+		for seed := range current.games {
+			if _, played := base.games[seed]; played {
+				seeds = append(seeds, seed)
+			}
+		}
+		sort.Slice(seeds, func(i, j int) bool { return seeds[i] < seeds[j] })
+		if len(seeds) > 0 {
+			pairedDifference(&r, seeds, random)
+		} else {
+			unpairedDifference(&r)
+		}
+		r.Regressed = limits.check(r)
+		regressions = append(regressions, r)
+	}
+	return regressions
+}
+
+func pairedDifference(r *regression, seeds []uint64, random *rand.Rand) {
+	current := BenchmarkResult{Name: r.Agent}
+	base := BenchmarkResult{Name: r.Agent}
+	differences := make([]float64, len(seeds))
+	for i, seed := range seeds {
+		a, b := r.Current.games[seed], r.Baseline.games[seed]
+		current.Seeds = append(current.Seeds, seed)
+		current.Won = append(current.Won, a.Won)
+		base.Seeds = append(base.Seeds, seed)
+		base.Won = append(base.Won, b.Won)
+		differences[i] = float64(a.Score - b.Score)
+	}
+	c := compare(current, base, random)
+	r.Paired = len(seeds)
+	r.WinRate, r.WinRateLow, r.WinRateHigh = c.Difference, c.DifferenceLow, c.DifferenceHigh
+	r.PValue = c.PValue
+	r.MeanScore, r.MeanScoreLow, r.MeanScoreHigh = meanInterval(differences)
+}
+
+// unpairedDifference uses Newcombe's interval for the difference of the win
+// rates and the normal interval for the difference of the mean scores
+func unpairedDifference(r *regression) {
+	a, b := r.Current, r.Baseline
+	r.WinRate = a.WinRate() - b.WinRate()
+	r.WinRateLow = r.WinRate - math.Hypot(a.WinRate()-a.WinRateLow, b.WinRateHigh-b.WinRate())
+	r.WinRateHigh = r.WinRate + math.Hypot(a.WinRateHigh-a.WinRate(), b.WinRate()-b.WinRateLow)
+	_, va := meanVariance(intsToFloats(a.Scores))
+	_, vb := meanVariance(intsToFloats(b.Scores))
+	margin := z95 * math.Sqrt(va/float64(a.N)+vb/float64(b.N))
+	r.MeanScore = a.MeanScore - b.MeanScore
+	r.MeanScoreLow, r.MeanScoreHigh = r.MeanScore-margin, r.MeanScore+margin
+}
+
+// check returns the measures that dropped more than allowed
+func (t thresholds) check(r regression) []string {
+	var regressed []string
+	winDrop, scoreDrop := -r.WinRate, -r.MeanScore
+	if t.Confident {
+		winDrop, scoreDrop = -r.WinRateHigh, -r.MeanScoreHigh
+	}
+	if t.WinRate >= 0 && 100*winDrop > t.WinRate {
+		regressed = append(regressed, fmt.Sprintf("win rate %+.1f %%", 100*r.WinRate))
+	}
+	if t.MeanScore >= 0 && scoreDrop > t.MeanScore {
+		regressed = append(regressed, fmt.Sprintf("mean score %+.1f", r.MeanScore))
+	}
+	return regressed
+}
+
+// reportCharts plots the win rate and the mean score against the iterations
+// searched per move, one series for every eval. The charts are SVG.
+func reportCharts(runs []reportRun) []string {
+	var evals []string
+	winRates := make(map[string][]chartPoint)
+	scores := make(map[string][]chartPoint)
+	for _, run := range runs {
+		for _, a := range run.Agents {
+			if a.Iterations <= 0 {
+				continue
+			}
+			label := a.Name
+			if len(runs) > 1 {
+				label = filepath.Base(run.Dir) + " " + a.Name
+			}
+			if _, seen := winRates[a.Eval]; !seen {
+				evals = append(evals, a.Eval)
+			}
+			winRates[a.Eval] = append(winRates[a.Eval], chartPoint{
+				X: a.Iterations, Y: 100 * a.WinRate(), Low: 100 * a.WinRateLow, High: 100 * a.WinRateHigh,
+				Label: fmt.Sprintf("%s: %.1f %%", label, 100*a.WinRate()),
+			})
+			scores[a.Eval] = append(scores[a.Eval], chartPoint{
+				X: a.Iterations, Y: a.MeanScore, Low: a.MeanScoreLow, High: a.MeanScoreHigh,
+				Label: fmt.Sprintf("%s: %.1f", label, a.MeanScore),
+			})
+		}
+	}
+	series := func(points map[string][]chartPoint) []chartSeries {
+		all := make([]chartSeries, len(evals))
+		for i, eval := range evals {
+			p := points[eval]
+			sort.Slice(p, func(i, j int) bool { return p[i].X < p[j].X })
+			all[i] = chartSeries{Name: eval, Points: p}
+		}
+		return all
+	}
+	const xLabel = "iterations per move"
+	return []string{
+		chartSVG("Win rate by compute", xLabel, "win rate %", series(winRates)),
+		chartSVG("Mean score by compute", xLabel, "mean score", series(scores)),
+	}
+}
+
+// The tables of the report as rows of cells, the first row being the header
+
+func agentTable(run reportRun) [][]string {
+	rows := [][]string{{"Agent", "Eval", "Budget", "Iterations / move", "Games", "Win rate [95 % CI]",
+		"Mean score [95 % CI]", "Median score", "Median cleared", "ms / move"}}
+	for _, a := range run.Agents {
+		moveTime := time.Duration(0)
+		if a.Moves > 0 {
+			moveTime = a.MoveTime / time.Duration(a.Moves)
+		}
+		rows = append(rows, []string{
+			a.Name, a.Eval, a.Budget,
+			fmt.Sprintf("%.0f", a.Iterations),
+			fmt.Sprintf("%d", a.N),
+			fmt.Sprintf("%.1f %% [%.1f, %.1f]", 100*a.WinRate(), 100*a.WinRateLow, 100*a.WinRateHigh),
+			fmt.Sprintf("%.1f [%.1f, %.1f]", a.MeanScore, a.MeanScoreLow, a.MeanScoreHigh),
+			fmt.Sprintf("%.0f", medianInts(a.Scores)),
+			fmt.Sprintf("%.0f %%", 100*median(a.ClearRates)),
+			fmt.Sprintf("%.1f", float64(moveTime)/float64(time.Millisecond)),
+		})
+	}
+	return rows
+}
+
+func regressionTable(regressions []regression) [][]string {
+	rows := [][]string{{"Run", "Agent", "Deals", "Win rate", "Baseline", "Difference [95 % CI]", "p",
+		"Mean score", "Baseline", "Difference [95 % CI]", "Status"}}
+	for _, r := range regressions {
+		deals := fmt.Sprintf("%d / %d", r.Current.N, r.Baseline.N)
+		p := "-"
+		if r.Paired > 0 {
+			deals = fmt.Sprintf("%d paired", r.Paired)
+			p = fmt.Sprintf("%.3g", r.PValue)
+		}
+		status := "ok"
+		if len(r.Regressed) > 0 {
+			status = "REGRESSED: " + strings.Join(r.Regressed, ", ")
+		}
+		rows = append(rows, []string{
+			filepath.Base(r.Run), r.Agent, deals,
+			fmt.Sprintf("%.1f %%", 100*r.Current.WinRate()),
+			fmt.Sprintf("%.1f %%", 100*r.Baseline.WinRate()),
+			fmt.Sprintf("%+.1f [%+.1f, %+.1f]", 100*r.WinRate, 100*r.WinRateLow, 100*r.WinRateHigh),
+			p,
+			fmt.Sprintf("%.1f", r.Current.MeanScore),
+			fmt.Sprintf("%.1f", r.Baseline.MeanScore),
+			fmt.Sprintf("%+.1f [%+.1f, %+.1f]", r.MeanScore, r.MeanScoreLow, r.MeanScoreHigh),
+			status,
+		})
+	}
+	return rows
+}
+
+func runSummary(run reportRun) string {
+	return fmt.Sprintf("%d deals from seed %d, baseline agent %s", len(run.Config.DealSeeds()), run.Config.Seed, run.Config.BaselineName())
+}
+
+// errWriter keeps the first error of the writes so that the reports need
+// not check every one
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (e *errWriter) printf(format string, args ...interface{}) {
+	if e.err == nil {
+		_, e.err = fmt.Fprintf(e.w, format, args...)
+	}
+}
+
+// writeMarkdownReport writes the report in Markdown with the charts inline
+// as raw SVG
+func writeMarkdownReport(w io.Writer, runs []reportRun, baseline *reportRun, regressions []regression, charts []string) error {
+	out := &errWriter{w: w}
+	out.printf("# Benchmark report\n\n")
+	for _, run := range runs {
+		out.printf("## %s\n\n%s.\n\n", run.Dir, runSummary(run))
+		markdownTable(out, agentTable(run))
+	}
+	out.printf("## Compute\n\n")
+	for _, chart := range charts {
+		out.printf("%s\n", chart)
+	}
+	if baseline != nil {
+		out.printf("## Compared to %s\n\n", baseline.Dir)
+		if len(regressions) == 0 {
+			out.printf("No agents in common with the baseline run.\n\n")
+		} else {
+			markdownTable(out, regressionTable(regressions))
+		}
+	}
+	return out.err
+}
+
+func markdownTable(out *errWriter, rows [][]string) {
+	for i, row := range rows {
+		cells := make([]string, len(row))
+		for j, cell := range row {
+			cells[j] = strings.Replace(cell, "|", `\|`, -1)
+		}
+		out.printf("| %s |\n", strings.Join(cells, " | "))
+		if i == 0 {
+			out.printf("|%s\n", strings.Repeat(" --- |", len(row)))
+		}
+	}
+	out.printf("\n")
+}
+
+func writeHTMLReport(w io.Writer, runs []reportRun, baseline *reportRun, regressions []regression, charts []string) error {
+	out := &errWriter{w: w}
+	out.printf(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Benchmark report</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: right; }
+th:first-child, td:first-child { text-align: left; }
+.regressed { color: #d62728; font-weight: bold; }
+</style>
+</head>
+<body>
+<h1>Benchmark report</h1>
+`)
+	for _, run := range runs {
+		out.printf("<h2>%s</h2>\n<p>%s.</p>\n", html.EscapeString(run.Dir), html.EscapeString(runSummary(run)))
+		htmlTable(out, agentTable(run))
+	}
+	out.printf("<h2>Compute</h2>\n")
+	for _, chart := range charts {
+		out.printf("%s", chart)
+	}
+	if baseline != nil {
+		out.printf("<h2>Compared to %s</h2>\n", html.EscapeString(baseline.Dir))
+		if len(regressions) == 0 {
+			out.printf("<p>No agents in common with the baseline run.</p>\n")
+		} else {
+			htmlTable(out, regressionTable(regressions))
+		}
+	}
+	out.printf("</body>\n</html>\n")
+	return out.err
+}
+
+func htmlTable(out *errWriter, rows [][]string) {
+	out.printf("<table>\n")
+	for i, row := range rows {
+		out.printf("<tr>")
+		for _, cell := range row {
+			switch {
+			case i == 0:
+				out.printf("<th>%s</th>", html.EscapeString(cell))
+			case strings.HasPrefix(cell, "REGRESSED"):
+				out.printf(`<td class="regressed">%s</td>`, html.EscapeString(cell))
+			default:
+				out.printf("<td>%s</td>", html.EscapeString(cell))
+			}
+		}
+		out.printf("</tr>\n")
+	}
+	out.printf("</table>\n")
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeRun writes a run directory of an agent that played the deals 1 to n
+// winning the first wins of them with the given score
+func writeRun(t *testing.T, n, wins, score int) string {
+	t.Helper()
+	dir := t.TempDir()
+	config := Config{Agents: []AgentConfig{{Name: "agent", Player: "random"}}}
+	for seed := 1; seed <= n; seed++ {
+		config.Seeds = append(config.Seeds, uint64(seed))
+	}
+	data, err := json.Marshal(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, experimentFile), data, 0666); err != nil {
+		t.Fatal(err)
+	}
+	gameLog, err := openLog(filepath.Join(dir, gamesFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer gameLog.Close()
+	for seed := 1; seed <= n; seed++ {
+		record := GameRecord{Agent: "agent", Seed: uint64(seed), Score: score, Cards: 28, Won: seed <= wins}
+		if record.Won {
+			record.CardsCleared = 28
+		}
+		if err := gameLog.write(record); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestThresholdsCheck(t *testing.T) {
+	r := regression{WinRate: -0.2, WinRateHigh: -0.05, MeanScore: -30, MeanScoreHigh: 5}
+	tests := []struct {
+		limits thresholds
+		want   int
+	}{
+		{thresholds{WinRate: -1, MeanScore: -1}, 0},
+		{thresholds{WinRate: 10, MeanScore: -1}, 1},
+		{thresholds{WinRate: 25, MeanScore: -1}, 0},
+		{thresholds{WinRate: -1, MeanScore: 20}, 1},
+		{thresholds{WinRate: 10, MeanScore: 20}, 2},
+		// The upper ends of the intervals drop by 5 points and rise by 5
+		{thresholds{WinRate: 10, MeanScore: 20, Confident: true}, 0},
+		{thresholds{WinRate: 4, MeanScore: 20, Confident: true}, 1},
+	}
+	for _, test := range tests {
+		if regressed := test.limits.check(r); len(regressed) != test.want {
+			t.Errorf("%+v regressed %q, want %d measures", test.limits, regressed, test.want)
+		}
+	}
+}
+
+// reportArgs runs reportMain with the arguments in a test process of its own
+// when set, as it exits the process on regressions
+const reportArgs = "TRIPEAKS_REPORT_ARGS"
+
+func TestReportMainProcess(t *testing.T) {
+	args := os.Getenv(reportArgs)
+	if args == "" {
+		t.Skip("only run by TestReportExitsOnRegression")
+	}
+	reportMain(strings.Split(args, "\n"))
+}
+
+func TestReportExitsOnRegression(t *testing.T) {
+	baseline := writeRun(t, 20, 16, 50)
+	same := writeRun(t, 20, 16, 50)
+	worse := writeRun(t, 20, 4, 10)
+	tests := []struct {
+		name string
+		run  string
+		args []string
+		fail bool
+	}{
+		{"no thresholds", worse, nil, false},
+		{"win rate below", worse, []string{"-max-win-drop", "10"}, true},
+		{"win rate above", worse, []string{"-max-win-drop", "70"}, false},
+		{"score below", worse, []string{"-max-score-drop", "30"}, true},
+		{"score above", worse, []string{"-max-score-drop", "50"}, false},
+		{"unchanged", same, []string{"-max-win-drop", "0", "-max-score-drop", "0"}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			report := filepath.Join(t.TempDir(), "report.md")
+			args := append([]string{"-baseline", baseline, "-out", report}, test.args...)
+			args = append(args, test.run)
+			cmd := exec.Command(os.Args[0], "-test.run=^TestReportMainProcess$")
+			cmd.Env = append(os.Environ(), reportArgs+"="+strings.Join(args, "\n"))
+			output, err := cmd.CombinedOutput()
+			var exit *exec.ExitError
+			if err != nil && !errors.As(err, &exit) {
+				t.Fatal(err)
+			}
+			if failed := err != nil; failed != test.fail {
+				t.Fatalf("report failed %v, want %v\n%s", failed, test.fail, output)
+			}
+			if test.fail && exit.ExitCode() != 1 {
+				t.Fatalf("report exited with %d, want 1\n%s", exit.ExitCode(), output)
+			}
+			// The report is written either way
+			if data, err := os.ReadFile(report); err != nil || !strings.Contains(string(data), "agent") {
+				t.Fatalf("report not written: %v\n%s", err, output)
+			}
+		})
+	}
+}
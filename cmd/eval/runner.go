@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/MatiasLyyra/TriPeaks/agent"
+)
+
+// The files of a run directory
+const (
+	experimentFile = "experiment.json"
+	gamesFile      = "games.jsonl"
+	resultsFile    = "results.csv"
+	pairedFile     = "paired.csv"
+	histogramFile  = "histogram.csv"
+)
+
+// job is a game to play
+type job struct {
+	agent int
+	seed  uint64
+}
+
+// runExperiment plays the games of the experiment that are not in the game
+// log of the run directory yet and writes the results of all of them
+func runExperiment(config Config, dir string) {
+	if err := config.Validate(); err != nil {
+		log.Fatalf("invalid config: %s", err)
+	}
+	fmt.Printf("Dealing with seed %d\n", config.Seed)
+	seeds := config.DealSeeds()
+	logPath := filepath.Join(dir, gamesFile)
+	records := make(map[recordKey]GameRecord)
+	previous, err := readLog(logPath)
+	if err != nil && !os.IsNotExist(err) {
+		log.Fatalf("failed to read %s: %s", logPath, err)
+	}
+	for _, record := range previous {
+		records[recordKey{record.Agent, record.Seed}] = record
+	}
+
+	options := make([]BenchmarkOptions, len(config.Agents))
+	var jobs []job
+	for i, a := range config.Agents {
+		if options[i], _, err = a.options(config); err != nil {
+			log.Fatalf("agent %q: %s", a.Name, err)
+		}
+		for _, seed := range seeds {
+			if _, done := records[recordKey{a.Name, seed}]; !done {
+				jobs = append(jobs, job{i, seed})
+			}
+		}
+	}
+	if len(previous) > 0 {
+		fmt.Printf("Resuming with %d games played and %d to play\n", len(records), len(jobs))
+	}
+
+	gameLog, err := openLog(logPath)
+	if err != nil {
+		log.Fatalf("failed to open %s: %s", logPath, err)
+	}
+	defer gameLog.Close()
+	played := 0
+	for record := range playGames(config, options, jobs) {
+		if err := gameLog.write(record); err != nil {
+			log.Fatalf("failed to write %s: %s", logPath, err)
+		}
+		records[recordKey{record.Agent, record.Seed}] = record
+		played++
+		outcome := "lost"
+		if record.Won {
+			outcome = "won"
+		}
+		deal := ""
+		if record.OracleUnknown {
+			deal = ", the solver gave up on the deal"
+		} else if !record.OracleWinnable {
+			deal = ", the deal cannot be won"
+		}
+		fmt.Printf("%s %s seed %d with score %d%s (%d/%d)\n", record.Agent, outcome, record.Seed, record.Score, deal, played, len(jobs))
+	}
+
+	results := make([]BenchmarkResult, len(config.Agents))
+	for i := range config.Agents {
+		results[i] = aggregate(options[i], seeds, records)
+	}
+	var comparisons []Comparison
+	if base, found := findResult(results, config.BaselineName()); found {
+		comparisons = comparePaired(results, base, config.Seed)
+		printComparisons(comparisons, os.Stdout)
+	}
+	saveResults(dir, results, comparisons)
+}
+
+// playGames plays the jobs with Config.Workers workers, each with its own
+// copy of every agent, and sends the records as the games finish
+func playGames(config Config, options []BenchmarkOptions, jobs []job) <-chan GameRecord {
+	workers := config.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	// The players are created up front, some policies are trained when they
+	// are created. Agents without games left to play are not created.
+	needed := make([]bool, len(config.Agents))
+	for _, j := range jobs {
+		needed[j.agent] = true
+	}
+	players := make([][]agent.Player, workers)
+	for w := range players {
+		players[w] = make([]agent.Player, len(config.Agents))
+		for i, a := range config.Agents {
+			if !needed[i] {
+				continue
+			}
+			var err error
+			if _, players[w][i], err = a.player(config); err != nil {
+				log.Fatalf("agent %q: %s", a.Name, err)
+			}
+		}
+	}
+	queue := make(chan job)
+	go func() {
+		for _, j := range jobs {
+			queue <- j
+		}
+		close(queue)
+	}()
+	records := make(chan GameRecord)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(players []agent.Player) {
+			defer wg.Done()
+			for j := range queue {
+				records <- playGame(options[j.agent], players[j.agent], j.seed)
+			}
+		}(players[w])
+	}
+	go func() {
+		wg.Wait()
+		close(records)
+	}()
+	return records
+}
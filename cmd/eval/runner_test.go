@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResumeAppendsUnfinishedGames(t *testing.T) {
+	dir := t.TempDir()
+	config := Config{
+		Seeds: []uint64{1, 2, 3},
+		Agents: []AgentConfig{
+			{Name: "first", Player: "random"},
+			{Name: "second", Player: "random"},
+		},
+	}
+	runExperiment(config, dir)
+	logPath := filepath.Join(dir, gamesFile)
+	played, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := bytes.SplitAfter(played, []byte("\n"))
+	if len(lines) != 7 || len(lines[6]) != 0 {
+		t.Fatalf("%d lines in the game log of 6 games", len(lines)-1)
+	}
+	original, err := readLog(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Keep the first four games and half of the fifth
+	kept := bytes.Join(lines[:4], nil)
+	cut := append(append([]byte(nil), kept...), lines[4][:len(lines[4])/2]...)
+	if err := os.WriteFile(logPath, cut, 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	runExperiment(config, dir)
+	resumed, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(resumed, cut) {
+		t.Fatalf("the games played before were rewritten")
+	}
+	if bytes.Count(resumed, []byte("\n")) != 7 {
+		t.Fatalf("the resumed log is not the 4 games kept, the cut line and 2 new games\n%s", resumed)
+	}
+	records, err := readLog(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 6 {
+		t.Fatalf("%d games after resuming, want 6", len(records))
+	}
+	for i, record := range records[:4] {
+		if record.Agent != original[i].Agent || record.Seed != original[i].Seed || record.Score != original[i].Score {
+			t.Fatalf("game %d changed from %+v to %+v", i, original[i], record)
+		}
+	}
+	games := make(map[recordKey]int)
+	for _, record := range records {
+		games[recordKey{record.Agent, record.Seed}]++
+	}
+	for _, a := range config.Agents {
+		for _, seed := range config.Seeds {
+			if games[recordKey{a.Name, seed}] != 1 {
+				t.Fatalf("%s played seed %d %d times", a.Name, seed, games[recordKey{a.Name, seed}])
+			}
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"math"
+	"strings"
+)
+
+// chartColors are given to the series in order
+var chartColors = []string{"#1f77b4", "#d62728", "#2ca02c", "#ff7f0e", "#9467bd", "#8c564b", "#e377c2", "#7f7f7f"}
+
+type chartPoint struct {
+	X, Y      float64
+	Low, High float64
+	Label     string
+}
+
+type chartSeries struct {
+	Name   string
+	Points []chartPoint
+}
+
+// The size of the charts and the margins around the plot
+const (
+	chartWidth  = 640
+	chartHeight = 360
+	marginLeft  = 60
+	marginRight = 160
+	marginTop   = 30
+	marginBot   = 50
+)
+
+// chartSVG draws the series as points with error bars joined by lines. The x
+// axis is logarithmic, every X must be positive.
+func chartSVG(title, xLabel, yLabel string, series []chartSeries) string {
+	minX, maxX := math.Inf(1), math.Inf(-1)
+	minY, maxY := math.Inf(1), math.Inf(-1)
+	for _, s := range series {
+		for _, p := range s.Points {
+			minX = math.Min(minX, p.X)
+			maxX = math.Max(maxX, p.X)
+			minY = math.Min(minY, math.Min(p.Y, p.Low))
+			maxY = math.Max(maxY, math.Max(p.Y, p.High))
+		}
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="sans-serif" font-size="12">`+"\n",
+		chartWidth, chartHeight, chartWidth, chartHeight)
+	fmt.Fprintf(&b, `<text x="%d" y="18" font-size="14">%s</text>`+"\n", marginLeft, html.EscapeString(title))
+	if math.IsInf(minX, 1) {
+		fmt.Fprintf(&b, `<text x="%d" y="%d">no data</text>`+"\n", marginLeft, chartHeight/2)
+		b.WriteString("</svg>\n")
+		return b.String()
+	}
+	// Whole decades on the x axis and round steps on the y axis
+	logMin := math.Floor(math.Log10(minX))
+	logMax := math.Ceil(math.Log10(maxX))
+	if logMax == logMin {
+		logMax++
+	}
+	if maxY == minY {
+		minY--
+		maxY++
+	}
+	step := tickStep((maxY - minY) / 4)
+	minY = math.Floor(minY/step) * step
+	maxY = math.Ceil(maxY/step) * step
+	plotWidth := float64(chartWidth - marginLeft - marginRight)
+	plotHeight := float64(chartHeight - marginTop - marginBot)
+	x := func(v float64) float64 {
+		return marginLeft + (math.Log10(v)-logMin)/(logMax-logMin)*plotWidth
+	}
+	y := func(v float64) float64 {
+		return marginTop + (maxY-v)/(maxY-minY)*plotHeight
+	}
+
+	// Axes and grid
+	fmt.Fprintf(&b, `<rect x="%d" y="%d" width="%.0f" height="%.0f" fill="none" stroke="#000"/>`+"\n", marginLeft, marginTop, plotWidth, plotHeight)
+	for decade := logMin; decade <= logMax; decade++ {
+		px := x(math.Pow(10, decade))
+		fmt.Fprintf(&b, `<line x1="%.1f" y1="%d" x2="%.1f" y2="%.0f" stroke="#ddd"/>`+"\n", px, marginTop, px, marginTop+plotHeight)
+		fmt.Fprintf(&b, `<text x="%.1f" y="%.0f" text-anchor="middle">%s</text>`+"\n", px, marginTop+plotHeight+16, formatTick(math.Pow(10, decade)))
+	}
+	for v := minY; v <= maxY+step/2; v += step {
+		py := y(v)
+		fmt.Fprintf(&b, `<line x1="%d" y1="%.1f" x2="%.0f" y2="%.1f" stroke="#ddd"/>`+"\n", marginLeft, py, marginLeft+plotWidth, py)
+		fmt.Fprintf(&b, `<text x="%d" y="%.1f" text-anchor="end">%s</text>`+"\n", marginLeft-4, py+4, formatTick(v))
+	}
+	fmt.Fprintf(&b, `<text x="%.0f" y="%d" text-anchor="middle">%s</text>`+"\n", marginLeft+plotWidth/2, chartHeight-10, html.EscapeString(xLabel))
+	fmt.Fprintf(&b, `<text x="14" y="%.0f" text-anchor="middle" transform="rotate(-90 14 %.0f)">%s</text>`+"\n",
+		marginTop+plotHeight/2, marginTop+plotHeight/2, html.EscapeString(yLabel))
+
+	// Series and legend
+	for i, s := range series {
+		color := chartColors[i%len(chartColors)]
+		points := make([]string, len(s.Points))
+		for j, p := range s.Points {
+			points[j] = fmt.Sprintf("%.1f,%.1f", x(p.X), y(p.Y))
+		}
+		if len(points) > 1 {
+			fmt.Fprintf(&b, `<polyline points="%s" fill="none" stroke="%s"/>`+"\n", strings.Join(points, " "), color)
+		}
+		for _, p := range s.Points {
+			px := x(p.X)
+			fmt.Fprintf(&b, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="%s"/>`+"\n", px, y(p.Low), px, y(p.High), color)
+			fmt.Fprintf(&b, `<circle cx="%.1f" cy="%.1f" r="3.5" fill="%s"><title>%s</title></circle>`+"\n",
+				px, y(p.Y), color, html.EscapeString(p.Label))
+		}
+		ly := marginTop + 10 + 18*i
+		lx := chartWidth - marginRight + 12
+		fmt.Fprintf(&b, `<rect x="%d" y="%d" width="10" height="10" fill="%s"/>`+"\n", lx, ly-9, color)
+		fmt.Fprintf(&b, `<text x="%d" y="%d">%s</text>`+"\n", lx+14, ly, html.EscapeString(s.Name))
+	}
+	b.WriteString("</svg>\n")
+	return b.String()
+}
+
+// tickStep rounds the step up to 1, 2 or 5 times a power of ten
+func tickStep(step float64) float64 {
+	magnitude := math.Pow(10, math.Floor(math.Log10(step)))
+	for _, m := range []float64{1, 2, 5} {
+		if m*magnitude >= step {
+			return m * magnitude
+		}
+	}
+	return 10 * magnitude
+}
+
+func formatTick(v float64) string {
+	switch {
+	case math.Abs(v) >= 1e6:
+		return fmt.Sprintf("%gM", v/1e6)
+	case math.Abs(v) >= 1e3:
+		return fmt.Sprintf("%gk", v/1e3)
+	}
+	// Adding zero turns a negative zero positive
+	return fmt.Sprintf("%g", math.Round(v*1000)/1000+0)
+}
@@ -5,57 +5,127 @@ import (
 	"strconv"
 )
 
+type Suit int
+
 const (
-	Hearts = iota
+	Hearts Suit = iota
 	Spades
 	Clubs
 	Diamonds
 )
 
+type Rank int
+
+const (
+	Two Rank = iota + 2
+	Three
+	Four
+	Five
+	Six
+	Seven
+	Eight
+	Nine
+	Ten
+	Jack
+	Queen
+	King
+	Ace
+)
+
 type Card struct {
-	Rank     int
-	Suit     int
+	Rank     Rank
+	Suit     Suit
 	FaceDown bool
 }
 
-func (c Card) String() string {
-	var suit string
-	var rank string
-	if c.Rank < 10 {
-		rank = strconv.Itoa(c.Rank)
-	} else {
-		switch c.Rank {
-		case 10:
-			rank = "T"
-		case 11:
-			rank = "J"
-		case 12:
-			rank = "Q"
-		case 13:
-			rank = "K"
-		case 14:
-			rank = "A"
-		default:
-			rank = "?"
-		}
+func (s Suit) Valid() bool {
+	return s >= Hearts && s <= Diamonds
+}
+
+// String returns the suit as a Unicode glyph
+func (s Suit) String() string {
+	switch s {
+	case Hearts:
+		return "♥"
+	case Spades:
+		return "♠"
+	case Diamonds:
+		return "♦"
+	case Clubs:
+		return "♣"
 	}
-	switch c.Suit {
+	return "?"
+}
+
+// Letter returns the suit as a lower case ASCII letter
+func (s Suit) Letter() string {
+	switch s {
 	case Hearts:
-		suit = "♥"
+		return "h"
 	case Spades:
-		suit = "♠"
+		return "s"
 	case Diamonds:
-		suit = "♦"
+		return "d"
 	case Clubs:
-		suit = "♣"
+		return "c"
+	}
+	return "?"
+}
+
+func (r Rank) Valid() bool {
+	return r >= Two && r <= Ace
+}
+
+func (r Rank) String() string {
+	if r >= Two && r < Ten {
+		return strconv.Itoa(int(r))
+	}
+	switch r {
+	case Ten:
+		return "T"
+	case Jack:
+		return "J"
+	case Queen:
+		return "Q"
+	case King:
+		return "K"
+	case Ace:
+		return "A"
 	}
+	return "?"
+}
+
+// Short returns the two character ASCII form of the card, e.g. As or Th
+func (c Card) Short() string {
+	return c.Rank.String() + c.Suit.Letter()
+}
+
+func (c Card) String() string {
 	if c.FaceDown {
 		return fmt.Sprintf("[    ]")
 	}
-	return fmt.Sprintf("[%s  %s]", string(rank), string(suit))
+	return fmt.Sprintf("[%s  %s]", c.Rank, c.Suit)
 
 }
 
 func (c Card) HashCode() int {
-	return c.Suit*100 + c.Rank
+	return int(c.Suit)*100 + int(c.Rank)
+}
+
+// Index returns a unique number between 0 and 51 for each card of the deck
+func (c Card) Index() int {
+	return int(c.Suit)*13 + int(c.Rank-Two)
+}
+
+// FromIndex returns the card matching the number returned by Card.Index
+func FromIndex(index int) Card {
+	return Card{
+		Rank: Rank(index%13) + Two,
+		Suit: Suit(index / 13),
+	}
+}
+
+// Valid reports whether the card has a rank and suit of a normal deck
+func (c Card) Valid() bool {
+	return c.Rank.Valid() && c.Suit.Valid()
 }
@@ -0,0 +1,71 @@
+package deck
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+const codeBase = 32
+
+// Code encodes the order of a full 52 card deck as a short base-32 string.
+// The order is stored as its rank among all permutations of the deck
+// (Lehmer code), so any deck order can be restored with FromCode.
+func (d *Deck) Code() (string, error) {
+	if d.Len() != 52 {
+		return "", fmt.Errorf("deck has %d cards, code requires 52", d.Len())
+	}
+	var seen [52]bool
+	for _, card := range d.Cards {
+		if !card.Valid() {
+			return "", fmt.Errorf("invalid card rank %d suit %d", card.Rank, card.Suit)
+		}
+		if seen[card.Index()] {
+			return "", fmt.Errorf("duplicate card %s", card)
+		}
+		seen[card.Index()] = true
+	}
+	code := new(big.Int)
+	for i, card := range d.Cards {
+		digit := 0
+		for _, other := range d.Cards[i+1:] {
+			if other.Index() < card.Index() {
+				digit++
+			}
+		}
+		code.Mul(code, big.NewInt(int64(52-i)))
+		code.Add(code, big.NewInt(int64(digit)))
+	}
+	return code.Text(codeBase), nil
+}
+
+// FromCode restores the deck encoded by Deck.Code
+func FromCode(code string) (*Deck, error) {
+	n, ok := new(big.Int).SetString(code, codeBase)
+	if !ok || n.Sign() < 0 {
+		return nil, fmt.Errorf("invalid deck code %q", code)
+	}
+	var digits [52]int
+	radix := new(big.Int)
+	digit := new(big.Int)
+	for i := 51; i >= 0; i-- {
+		radix.SetInt64(int64(52 - i))
+		n.DivMod(n, radix, digit)
+		digits[i] = int(digit.Int64())
+	}
+	if n.Sign() != 0 {
+		return nil, errors.New("deck code is out of range")
+	}
+	remaining := make([]int, 52)
+	for i := range remaining {
+		remaining[i] = i
+	}
+	d := &Deck{
+		Cards: make([]Card, 0, 52),
+	}
+	for _, digit := range digits {
+		d.Cards = append(d.Cards, FromIndex(remaining[digit]))
+		remaining = append(remaining[:digit], remaining[digit+1:]...)
+	}
+	return d, nil
+}
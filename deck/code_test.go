@@ -0,0 +1,94 @@
+package deck
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+)
+
+func TestCodeRoundTrip(t *testing.T) {
+	ordered := &Deck{}
+	for i := 0; i < 52; i++ {
+		ordered.Cards = append(ordered.Cards, FromIndex(i))
+	}
+	reversed := &Deck{}
+	for i := 51; i >= 0; i-- {
+		reversed.Cards = append(reversed.Cards, FromIndex(i))
+	}
+	tests := []struct {
+		name string
+		deck *Deck
+	}{
+		{"ordered", ordered},
+		{"reversed", reversed},
+		{"new", New()},
+	}
+	for seed := uint64(1); seed <= 5; seed++ {
+		d := New()
+		d.ShuffleSeed(seed)
+		tests = append(tests, struct {
+			name string
+			deck *Deck
+		}{"shuffled", d})
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			code, err := test.deck.Code()
+			if err != nil {
+				t.Fatal(err)
+			}
+			restored, err := FromCode(code)
+			if err != nil {
+				t.Fatalf("failed to restore %q: %s", code, err)
+			}
+			if !reflect.DeepEqual(restored.Cards, test.deck.Cards) {
+				t.Fatalf("code %q restored\n%v\nwant\n%v", code, restored.Cards, test.deck.Cards)
+			}
+		})
+	}
+	if code, _ := ordered.Code(); code != "0" {
+		t.Fatalf("ordered deck has code %q, want 0", code)
+	}
+}
+
+func TestCodeRejectsInvalidDecks(t *testing.T) {
+	short := New()
+	short.Cards = short.Cards[1:]
+	duplicate := New()
+	duplicate.Cards[0] = duplicate.Cards[1]
+	invalid := New()
+	invalid.Cards[0].Rank = 0
+	tests := []struct {
+		name string
+		deck *Deck
+	}{
+		{"short", short},
+		{"duplicate", duplicate},
+		{"invalid card", invalid},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if code, err := test.deck.Code(); err == nil {
+				t.Fatalf("invalid deck encoded as %q", code)
+			}
+		})
+	}
+}
+
+func TestFromCodeRejectsInvalidCodes(t *testing.T) {
+	// 52! is one past the code of the last permutation
+	permutations := big.NewInt(1)
+	for i := int64(2); i <= 52; i++ {
+		permutations.Mul(permutations, big.NewInt(i))
+	}
+	tests := []string{"", "!", "zz!", "-1", permutations.Text(codeBase)}
+	for _, code := range tests {
+		if _, err := FromCode(code); err == nil {
+			t.Errorf("invalid code %q accepted", code)
+		}
+	}
+	last := new(big.Int).Sub(permutations, big.NewInt(1))
+	if _, err := FromCode(last.Text(codeBase)); err != nil {
+		t.Errorf("code of the last permutation rejected: %s", err)
+	}
+}
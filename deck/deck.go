@@ -16,7 +16,7 @@ func New() *Deck {
 	deck := Deck{
 		Cards: make([]Card, 0, 52),
 	}
-	for i := 2; i <= 14; i++ {
+	for i := Two; i <= Ace; i++ {
 		deck.Cards = append(deck.Cards, Card{
 			Rank:     i,
 			Suit:     Hearts,
@@ -52,10 +52,20 @@ func (d *Deck) Copy() *Deck {
 	return newDeck
 }
 
-// Shuffles the deck using rand.Shuffle
+// Shuffles the deck using a random source seeded with the current time
 func (d *Deck) Shuffle() {
-	rand.Seed(time.Now().UTC().UnixNano())
-	rand.Shuffle(len(d.Cards), func(i, j int) {
+	d.ShuffleRand(rand.New(rand.NewSource(time.Now().UTC().UnixNano())))
+}
+
+// ShuffleSeed shuffles the deck so that the same seed always produces the
+// same order
+func (d *Deck) ShuffleSeed(seed uint64) {
+	d.ShuffleRand(rand.New(rand.NewSource(int64(seed))))
+}
+
+// ShuffleRand shuffles the deck using the given random number generator
+func (d *Deck) ShuffleRand(random *rand.Rand) {
+	random.Shuffle(len(d.Cards), func(i, j int) {
 		temp := d.Cards[i]
 		d.Cards[i] = d.Cards[j]
 		d.Cards[j] = temp
@@ -72,7 +82,7 @@ func (d *Deck) Pop() (bool, Card) {
 		return false, card
 	}
 	card = d.Cards[d.Len()-1]
-	d.Cards = append(d.Cards[:d.Len()-1])
+	d.Cards = d.Cards[:d.Len()-1]
 	return true, card
 }
 
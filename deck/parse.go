@@ -0,0 +1,114 @@
+package deck
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ParseCard parses a single card. The rank is one of 2-9, T or 10, J, Q, K
+// and A, followed by the suit either as an ASCII letter (h, s, c, d) or a
+// Unicode glyph. The format printed by Card.String, e.g. [A  ♠], is also
+// accepted.
+func ParseCard(s string) (Card, error) {
+	card, rest, err := parseCard(strings.TrimFunc(s, isSeparator))
+	if err != nil {
+		return Card{}, err
+	}
+	if rest != "" {
+		return Card{}, fmt.Errorf("invalid card %q", s)
+	}
+	return card, nil
+}
+
+// ParseDeck parses a list of cards in any format accepted by ParseCard. The
+// cards can be separated by whitespace or commas, or written one after
+// another, e.g. "As Th 2c", "As,Th,2c" or "AsTh2c". The first card parsed
+// is the bottom of the deck.
+func ParseDeck(s string) (*Deck, error) {
+	d := &Deck{
+		Cards: make([]Card, 0, 52),
+	}
+	var seen [52]bool
+	rest := strings.TrimLeftFunc(s, isSeparator)
+	for rest != "" {
+		var (
+			card Card
+			err  error
+		)
+		card, rest, err = parseCard(rest)
+		if err != nil {
+			return nil, err
+		}
+		if seen[card.Index()] {
+			return nil, fmt.Errorf("duplicate card %s", card.Short())
+		}
+		seen[card.Index()] = true
+		d.Cards = append(d.Cards, card)
+		rest = strings.TrimLeftFunc(rest, isSeparator)
+	}
+	return d, nil
+}
+
+// parseCard parses the card at the start of s and returns the rest of s
+func parseCard(s string) (Card, string, error) {
+	var card Card
+	rank, size := parseRank(s)
+	if !rank.Valid() {
+		return card, "", fmt.Errorf("invalid card %q", s)
+	}
+	rest := strings.TrimLeftFunc(s[size:], unicode.IsSpace)
+	r, size := utf8.DecodeRuneInString(rest)
+	suit := parseSuit(r)
+	if !suit.Valid() {
+		return card, "", fmt.Errorf("invalid card %q", s)
+	}
+	card.Rank = rank
+	card.Suit = suit
+	return card, rest[size:], nil
+}
+
+// parseRank parses the rank at the start of s and returns the number of bytes
+// it used
+func parseRank(s string) (Rank, int) {
+	if strings.HasPrefix(s, "10") {
+		return Ten, 2
+	}
+	if s == "" {
+		return 0, 0
+	}
+	switch c := s[0]; {
+	case c >= '2' && c <= '9':
+		return Rank(c - '0'), 1
+	case c == 'T' || c == 't':
+		return Ten, 1
+	case c == 'J' || c == 'j':
+		return Jack, 1
+	case c == 'Q' || c == 'q':
+		return Queen, 1
+	case c == 'K' || c == 'k':
+		return King, 1
+	case c == 'A' || c == 'a':
+		return Ace, 1
+	}
+	return 0, 0
+}
+
+func parseSuit(r rune) Suit {
+	switch r {
+	case 'h', 'H', '♥', '♡':
+		return Hearts
+	case 's', 'S', '♠', '♤':
+		return Spades
+	case 'c', 'C', '♣', '♧':
+		return Clubs
+	case 'd', 'D', '♦', '♢':
+		return Diamonds
+	}
+	return -1
+}
+
+func isSeparator(r rune) bool {
+	return unicode.IsSpace(r) || r == ',' || r == '[' || r == ']'
+}
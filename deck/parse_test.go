@@ -0,0 +1,89 @@
+package deck
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseCardRoundTrip(t *testing.T) {
+	for i := 0; i < 52; i++ {
+		card := FromIndex(i)
+		for _, s := range []string{card.Short(), card.String()} {
+			parsed, err := ParseCard(s)
+			if err != nil {
+				t.Fatalf("failed to parse %q: %s", s, err)
+			}
+			if parsed != card {
+				t.Fatalf("parsed %q as %v, want %v", s, parsed, card)
+			}
+		}
+	}
+}
+
+func TestParseCard(t *testing.T) {
+	tests := []struct {
+		s     string
+		want  Card
+		valid bool
+	}{
+		{"As", Card{Rank: Ace, Suit: Spades}, true},
+		{"th", Card{Rank: Ten, Suit: Hearts}, true},
+		{"10h", Card{Rank: Ten, Suit: Hearts}, true},
+		{"Q♦", Card{Rank: Queen, Suit: Diamonds}, true},
+		{"2♧", Card{Rank: Two, Suit: Clubs}, true},
+		{" Kc, ", Card{Rank: King, Suit: Clubs}, true},
+		{"[J  ♥]", Card{Rank: Jack, Suit: Hearts}, true},
+		{"", Card{}, false},
+		{"A", Card{}, false},
+		{"1h", Card{}, false},
+		{"Ax", Card{}, false},
+		{"Ahh", Card{}, false},
+		{"As Kd", Card{}, false},
+	}
+	for _, test := range tests {
+		card, err := ParseCard(test.s)
+		if test.valid && (err != nil || card != test.want) {
+			t.Errorf("ParseCard(%q) = %v, %v, want %v", test.s, card, err, test.want)
+		}
+		if !test.valid && err == nil {
+			t.Errorf("ParseCard(%q) accepted %v", test.s, card)
+		}
+	}
+}
+
+func TestParseDeck(t *testing.T) {
+	want := []Card{{Rank: Ace, Suit: Spades}, {Rank: Ten, Suit: Hearts}, {Rank: Two, Suit: Clubs}}
+	for _, s := range []string{"As Th 2c", "As,Th,2c", "AsTh2c", " [A  ♠] [T  ♥] [2  ♣] ", "A♠, 10♥, 2♣"} {
+		d, err := ParseDeck(s)
+		if err != nil {
+			t.Fatalf("failed to parse %q: %s", s, err)
+		}
+		if !reflect.DeepEqual(d.Cards, want) {
+			t.Fatalf("parsed %q as %v, want %v", s, d.Cards, want)
+		}
+	}
+	if d, err := ParseDeck(""); err != nil || d.Len() != 0 {
+		t.Fatalf("empty deck parsed as %v, %v", d, err)
+	}
+	for _, s := range []string{"As As", "As Xx", "AsT", "As 1h"} {
+		if _, err := ParseDeck(s); err == nil {
+			t.Errorf("invalid deck %q accepted", s)
+		}
+	}
+}
+
+func TestParseDeckRoundTrip(t *testing.T) {
+	d := New()
+	d.ShuffleSeed(11)
+	s := ""
+	for _, card := range d.Cards {
+		s += card.Short()
+	}
+	parsed, err := ParseDeck(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(parsed.Cards, d.Cards) {
+		t.Fatalf("parsed\n%v\nwant\n%v", parsed.Cards, d.Cards)
+	}
+}
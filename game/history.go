@@ -0,0 +1,201 @@
+package game
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/MatiasLyyra/TriPeaks/deck"
+)
+
+type MoveKind int
+
+const (
+	MoveSelect MoveKind = iota
+	MoveDraw
+	MoveSurrender
+)
+
+// Move is a single action taken in the game. Pos is only used by MoveSelect.
+type Move struct {
+	Kind MoveKind
+	Pos  int
+}
+
+// MoveFromPos converts a move in the format returned by LegalMoves,
+// where -1 means drawing a card, into a Move.
+func MoveFromPos(pos int) Move {
+	if pos == -1 {
+		return Move{Kind: MoveDraw}
+	}
+	return Move{Kind: MoveSelect, Pos: pos}
+}
+
+func (m Move) String() string {
+	switch m.Kind {
+	case MoveSelect:
+		return fmt.Sprintf("select %d", m.Pos)
+	case MoveDraw:
+		return "draw"
+	case MoveSurrender:
+		return "surrender"
+	}
+	return "unknown"
+}
+
+// ParseMove parses a move in the format returned by Move.String
+func ParseMove(s string) (Move, error) {
+	switch s {
+	case "draw":
+		return Move{Kind: MoveDraw}, nil
+	case "surrender":
+		return Move{Kind: MoveSurrender}, nil
+	}
+	if !strings.HasPrefix(s, "select ") {
+		return Move{}, fmt.Errorf("invalid move %q", s)
+	}
+	pos, err := strconv.Atoi(strings.TrimPrefix(s, "select "))
+	if err != nil || pos < 0 {
+		return Move{}, fmt.Errorf("invalid move %q", s)
+	}
+	return Move{Kind: MoveSelect, Pos: pos}, nil
+}
+
+// undoRecord holds what is needed to take a move back
+type undoRecord struct {
+	move       Move
+	scoreDelta int
+	streak     int
+	cardsLeft  int
+	// The stock was recycled before drawing
+	recycled bool
+	// Slots that were still on the tableau when surrendering
+	removed []int
+}
+
+// Play applies the move and records it in the history. Any moves that were
+// undone are discarded. Returns false if the move is not legal.
+func (tri *TriPeaks) Play(move Move) bool {
+	if !tri.play(move) {
+		return false
+	}
+	tri.redo = tri.redo[:0]
+	return true
+}
+
+// Apply plays the move without recording it, for play-outs that never take
+// moves back. The history is dropped so that Undo cannot take back an
+// earlier move in its place. Returns false if the move is not legal.
+func (tri *TriPeaks) Apply(move Move) bool {
+	if _, ok := tri.apply(move); !ok {
+		return false
+	}
+	tri.history = tri.history[:0]
+	tri.redo = tri.redo[:0]
+	return true
+}
+
+func (tri *TriPeaks) play(move Move) bool {
+	record, ok := tri.apply(move)
+	if !ok {
+		return false
+	}
+	tri.history = append(tri.history, record)
+	return true
+}
+
+// apply makes the move and returns the record that takes it back
+func (tri *TriPeaks) apply(move Move) (undoRecord, bool) {
+	record := undoRecord{
+		move:      move,
+		streak:    tri.Streak,
+		cardsLeft: tri.CardsLeft,
+	}
+	score := tri.Score
+	switch move.Kind {
+	case MoveSelect:
+		if !tri.selectCard(move.Pos) {
+			return record, false
+		}
+	case MoveDraw:
+		record.recycled = tri.Stock.Len() == 0
+		if !tri.draw() {
+			return record, false
+		}
+	case MoveSurrender:
+		record.removed = tri.surrender()
+	default:
+		return record, false
+	}
+	record.scoreDelta = tri.Score - score
+	return record, true
+}
+
+// Moves returns the moves played so far, oldest first
+func (tri *TriPeaks) Moves() []Move {
+	moves := make([]Move, len(tri.history))
+	for i, record := range tri.history {
+		moves[i] = record.move
+	}
+	return moves
+}
+
+// Undo takes back the latest move, deducting the undo penalty of the rules.
+// Returns false if there is nothing to undo.
+func (tri *TriPeaks) Undo() bool {
+	if len(tri.history) == 0 {
+		return false
+	}
+	record := tri.history[len(tri.history)-1]
+	tri.history = tri.history[:len(tri.history)-1]
+	switch record.move.Kind {
+	case MoveSelect:
+		pos := record.move.Pos
+		for _, covered := range tri.Layout.Slots[pos].Covers {
+			tri.coverSlot(covered)
+		}
+		tri.Cards[pos].Removed = false
+		tri.hash ^= zobristRemoved[pos]
+		tri.removeDiscard()
+	case MoveDraw:
+		tri.hash ^= zobristStock[tri.Stock.Len()] ^ zobristStock[tri.Stock.Len()+1]
+		card := tri.removeDiscard()
+		tri.stockOrder ^= zobristOrder(&zobristStockOrder, tri.Stock.Len(), card)
+		tri.Stock.Cards = append(tri.Stock.Cards, card)
+		if record.recycled {
+			tri.unrecycle()
+		}
+	case MoveSurrender:
+		for _, pos := range record.removed {
+			tri.Cards[pos].Removed = false
+			tri.hash ^= zobristRemoved[pos]
+		}
+	}
+	tri.Score -= record.scoreDelta + tri.Rules.Scoring.UndoPenalty
+	tri.Streak = record.streak
+	tri.CardsLeft = record.cardsLeft
+	tri.redo = append(tri.redo, record)
+	return true
+}
+
+// Redo plays the latest undone move again. Returns false if there is nothing
+// to redo.
+func (tri *TriPeaks) Redo() bool {
+	if len(tri.redo) == 0 {
+		return false
+	}
+	record := tri.redo[len(tri.redo)-1]
+	tri.redo = tri.redo[:len(tri.redo)-1]
+	return tri.play(record.move)
+}
+
+// removeDiscard reverts AddDiscard and returns the removed card
+func (tri *TriPeaks) removeDiscard() deck.Card {
+	last := len(tri.Discards) - 1
+	card := tri.Discards[0]
+	tri.Discards[0] = tri.Discards[last]
+	tri.Discards = tri.Discards[:last]
+	tri.hash ^= zobristCard(card) ^ zobristCard(tri.Discards[0])
+	tri.pileOrder ^= zobristOrder(&zobristPileOrder, last, card)
+	return card
+}
@@ -0,0 +1,206 @@
+package game
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/MatiasLyyra/TriPeaks/deck"
+)
+
+// snapshot is the state of a game compared by the tests, the score apart
+type snapshot struct {
+	Streak    int
+	CardsLeft int
+	Recycles  int
+	Stock     []deck.Card
+	Discards  []deck.Card
+	Cards     TriPeaksDeck
+	Hash      uint64
+}
+
+func takeSnapshot(tri *TriPeaks) snapshot {
+	return snapshot{
+		Streak:    tri.Streak,
+		CardsLeft: tri.CardsLeft,
+		Recycles:  tri.Recycles,
+		Stock:     append([]deck.Card(nil), tri.Stock.Cards...),
+		Discards:  append([]deck.Card(nil), tri.Discards...),
+		Cards:     append(TriPeaksDeck(nil), tri.Cards...),
+		Hash:      tri.Hash(),
+	}
+}
+
+// greedyMove plays the first card that can be played, drawing if none can
+func greedyMove(tri *TriPeaks) Move {
+	moves, _ := tri.LegalMoves()
+	for _, move := range moves {
+		if move != -1 {
+			return MoveFromPos(move)
+		}
+	}
+	return MoveFromPos(moves[0])
+}
+
+func TestUndoRedo(t *testing.T) {
+	penalized := DefaultRules()
+	penalized.Scoring.UndoPenalty = 3
+	recycling := DefaultRules()
+	recycling.StockRecycles = 1
+	tests := []struct {
+		name  string
+		rules Rules
+		// moves are played, the last undo of them are taken back
+		moves int
+		undo  int
+		// until stops the moves early when it returns true
+		until func(tri *TriPeaks) bool
+		// surrender replaces the last move
+		surrender bool
+	}{
+		{name: "select", rules: DefaultRules(), moves: 1, undo: 1},
+		{name: "streak", rules: DefaultRules(), moves: 12, undo: 5},
+		{name: "undo penalty", rules: penalized, moves: 10, undo: 4},
+		{name: "whole game", rules: DefaultRules(), moves: 200, undo: 20},
+		{
+			name: "recycle", rules: recycling, moves: 200, undo: 1,
+			until: func(tri *TriPeaks) bool { return tri.Recycles == 1 },
+		},
+		{name: "surrender", rules: DefaultRules(), moves: 5, undo: 1, surrender: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stock := deck.New()
+			stock.ShuffleSeed(3)
+			tri := NewTripeaks(*stock, test.rules)
+			var states []snapshot
+			var scores []int
+			var played []Move
+			for i := 0; i < test.moves && !tri.GameOver(); i++ {
+				if test.until != nil && test.until(tri) {
+					break
+				}
+				states = append(states, takeSnapshot(tri))
+				scores = append(scores, tri.Score)
+				move := greedyMove(tri)
+				if test.surrender && i == test.moves-1 {
+					move = Move{Kind: MoveSurrender}
+				}
+				if !tri.Play(move) {
+					t.Fatalf("failed to play %s", move)
+				}
+				played = append(played, move)
+			}
+			if test.until != nil && !test.until(tri) {
+				t.Fatalf("the game did not reach the tested state")
+			}
+			final := takeSnapshot(tri)
+			finalScore := tri.Score
+			undo := test.undo
+			penalty := test.rules.Scoring.UndoPenalty
+			for i := 1; i <= undo; i++ {
+				if !tri.Undo() {
+					t.Fatalf("undo %d failed", i)
+				}
+				want := len(played) - i
+				if got := takeSnapshot(tri); !reflect.DeepEqual(got, states[want]) {
+					t.Fatalf("after undo %d the state is\n%+v\nwant\n%+v", i, got, states[want])
+				}
+				if tri.Score != scores[want]-i*penalty {
+					t.Fatalf("after undo %d the score is %d, want %d", i, tri.Score, scores[want]-i*penalty)
+				}
+				rehashed := tri.Copy()
+				rehashed.Rehash()
+				if rehashed.Hash() != tri.Hash() {
+					t.Fatalf("after undo %d the hash is %x, recomputed %x", i, tri.Hash(), rehashed.Hash())
+				}
+			}
+			kept := played[:len(played)-undo]
+			if got := tri.Moves(); len(got) != len(kept) || len(got) > 0 && !reflect.DeepEqual(got, kept) {
+				t.Fatalf("moves after undo are %v, want %v", got, kept)
+			}
+			for i := 1; i <= undo; i++ {
+				if !tri.Redo() {
+					t.Fatalf("redo %d failed", i)
+				}
+			}
+			if tri.Redo() {
+				t.Fatalf("redo succeeded with nothing to redo")
+			}
+			if got := takeSnapshot(tri); !reflect.DeepEqual(got, final) {
+				t.Fatalf("after redo the state is\n%+v\nwant\n%+v", got, final)
+			}
+			if tri.Score != finalScore-undo*penalty {
+				t.Fatalf("after redo the score is %d, want %d", tri.Score, finalScore-undo*penalty)
+			}
+		})
+	}
+}
+
+func TestPlayDiscardsRedo(t *testing.T) {
+	stock := deck.New()
+	stock.ShuffleSeed(3)
+	tri := NewTripeaks(*stock, DefaultRules())
+	tri.Play(greedyMove(tri))
+	tri.Play(greedyMove(tri))
+	tri.Undo()
+	tri.Play(Move{Kind: MoveDraw})
+	if tri.Redo() {
+		t.Fatalf("redo succeeded after a new move was played")
+	}
+	tri.Undo()
+	tri.Undo()
+	if tri.Undo() {
+		t.Fatalf("undo succeeded with nothing to undo")
+	}
+}
+
+func TestCopyStateIntoDropsHistory(t *testing.T) {
+	stock := deck.New()
+	stock.ShuffleSeed(4)
+	tri := NewTripeaks(*stock, DefaultRules())
+	for i := 0; i < 4; i++ {
+		tri.Play(greedyMove(tri))
+	}
+	tri.Undo()
+	full := tri.Copy()
+	if len(full.Moves()) != 3 || !full.Redo() {
+		t.Fatalf("Copy lost the history")
+	}
+	// The history of a reused game is dropped as well
+	dst := full.Copy()
+	tri.CopyStateInto(dst)
+	if got, want := takeSnapshot(dst), takeSnapshot(tri); !reflect.DeepEqual(got, want) {
+		t.Fatalf("the state copy is\n%+v\nwant\n%+v", got, want)
+	}
+	if dst.Score != tri.Score {
+		t.Fatalf("the state copy has score %d, want %d", dst.Score, tri.Score)
+	}
+	if len(dst.Moves()) != 0 || dst.Undo() || dst.Redo() {
+		t.Fatalf("the state copy has moves to undo or redo")
+	}
+}
+
+func TestApplyMatchesPlay(t *testing.T) {
+	recycling := DefaultRules()
+	recycling.StockRecycles = 1
+	stock := deck.New()
+	stock.ShuffleSeed(5)
+	played := NewTripeaks(*stock, recycling)
+	applied := played.Copy()
+	for !played.GameOver() {
+		move := greedyMove(played)
+		played.Play(move)
+		if !applied.Apply(move) {
+			t.Fatalf("Apply rejected %s", move)
+		}
+		if got, want := takeSnapshot(applied), takeSnapshot(played); !reflect.DeepEqual(got, want) || applied.Score != played.Score {
+			t.Fatalf("after applying %s the state is\n%+v\nwant\n%+v", move, got, want)
+		}
+		if applied.Undo() {
+			t.Fatalf("an applied move was undone")
+		}
+	}
+	if applied.Apply(Move{Kind: MoveSelect, Pos: 0}) {
+		t.Fatalf("Apply accepted an illegal move")
+	}
+}
@@ -0,0 +1,235 @@
+package game
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Slot is a single tableau position of a Layout
+type Slot struct {
+	// Covers lists the slots, at most two, that this card lies on top of. A
+	// card can only be played after every card covering it has been removed.
+	Covers []int
+	// Row and Col are the render coordinates. Col is measured in half card
+	// widths so that rows can be offset from each other.
+	Row int
+	Col int
+	// FaceUp deals the card face up even if it is covered
+	FaceUp bool
+}
+
+// Layout describes the geometry of the tableau. Cards are dealt to the slots
+// in order after the first discard.
+type Layout struct {
+	Name  string
+	Slots []Slot
+	// Peaks are the slots that give a bonus when removed. Removing all of
+	// them gives another bonus.
+	Peaks []int
+}
+
+var (
+	// TriPeaksLayout is the classic three peaks of 28 cards
+	TriPeaksLayout = peaksLayout("tripeaks", 3)
+	// FourPeaksLayout has four peaks and 37 cards
+	FourPeaksLayout = peaksLayout("fourpeaks", 4)
+	// PyramidLayout is a single peak of seven rows like in Pyramid solitaire
+	PyramidLayout = pyramidLayout()
+	// GolfLayout is seven columns of five face up cards like in Golf
+	// solitaire, only the bottom card of each column is free
+	GolfLayout = golfLayout()
+
+	Layouts = []*Layout{TriPeaksLayout, FourPeaksLayout, PyramidLayout, GolfLayout}
+)
+
+// NewLayout validates the slots and creates a new layout
+func NewLayout(name string, slots []Slot, peaks []int) (*Layout, error) {
+	if len(slots) == 0 || len(slots) > 51 {
+		return nil, fmt.Errorf("layout has %d slots, expected 1-51", len(slots))
+	}
+	layout := &Layout{
+		Name:  name,
+		Slots: slots,
+		Peaks: peaks,
+	}
+	for pos, slot := range slots {
+		if len(slot.Covers) > 2 {
+			return nil, fmt.Errorf("slot %d covers %d slots, at most 2 allowed", pos, len(slot.Covers))
+		}
+		for _, covered := range slot.Covers {
+			if covered < 0 || covered >= len(slots) {
+				return nil, fmt.Errorf("slot %d covers unknown slot %d", pos, covered)
+			}
+			if slots[covered].Row >= slot.Row {
+				return nil, fmt.Errorf("slot %d covers slot %d which is not on a row above it", pos, covered)
+			}
+		}
+	}
+	for _, peak := range peaks {
+		if peak < 0 || peak >= len(slots) {
+			return nil, fmt.Errorf("unknown peak slot %d", peak)
+		}
+	}
+	return layout, nil
+}
+
+func mustLayout(name string, slots []Slot, peaks []int) *Layout {
+	layout, err := NewLayout(name, slots, peaks)
+	if err != nil {
+		panic(err)
+	}
+	return layout
+}
+
+// LayoutByName returns the built-in layout with the given name
+func LayoutByName(name string) (*Layout, bool) {
+	for _, layout := range Layouts {
+		if layout.Name == name {
+			return layout, true
+		}
+	}
+	return nil, false
+}
+
+// IsPeak reports whether the slot is one of the peaks
+func (l *Layout) IsPeak(pos int) bool {
+	for _, peak := range l.Peaks {
+		if peak == pos {
+			return true
+		}
+	}
+	return false
+}
+
+// Render draws the cards at their slot coordinates
+func (l *Layout) Render(cards []PeakCard) string {
+	const halfWidth = 3
+	rows := make(map[int][]int)
+	rowNumbers := make([]int, 0)
+	for pos, slot := range l.Slots {
+		if _, exists := rows[slot.Row]; !exists {
+			rowNumbers = append(rowNumbers, slot.Row)
+		}
+		rows[slot.Row] = append(rows[slot.Row], pos)
+	}
+	sort.Ints(rowNumbers)
+	var b strings.Builder
+	for _, row := range rowNumbers {
+		slots := rows[row]
+		sort.Slice(slots, func(i, j int) bool {
+			return l.Slots[slots[i]].Col < l.Slots[slots[j]].Col
+		})
+		width := 0
+		for _, pos := range slots {
+			x := l.Slots[pos].Col * halfWidth
+			if x > width {
+				b.WriteString(strings.Repeat(" ", x-width))
+				width = x
+			}
+			b.WriteString(cards[pos].String())
+			width += 2 * halfWidth
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// peaksLayout builds a Tri Peaks style tableau with the given number of
+// peaks. Each peak is three rows high and the bottom row is shared.
+func peaksLayout(name string, peaks int) *Layout {
+	slots := make([]Slot, 0, 9*peaks+1)
+	peakSlots := make([]int, peaks)
+	for p := 0; p < peaks; p++ {
+		peakSlots[p] = p
+		slots = append(slots, Slot{Row: 0, Col: 3 + 6*p})
+	}
+	row1 := len(slots)
+	for k := 0; k < 2*peaks; k++ {
+		slots = append(slots, Slot{
+			Covers: []int{k / 2},
+			Row:    1,
+			Col:    2 + 6*(k/2) + 2*(k%2),
+		})
+	}
+	row2 := len(slots)
+	for k := 0; k < 3*peaks; k++ {
+		var covers []int
+		p := k / 3
+		switch k % 3 {
+		case 0:
+			covers = []int{row1 + 2*p}
+		case 1:
+			covers = []int{row1 + 2*p, row1 + 2*p + 1}
+		case 2:
+			covers = []int{row1 + 2*p + 1}
+		}
+		slots = append(slots, Slot{
+			Covers: covers,
+			Row:    2,
+			Col:    1 + 2*k,
+		})
+	}
+	for j := 0; j <= 3*peaks; j++ {
+		var covers []int
+		if j > 0 {
+			covers = append(covers, row2+j-1)
+		}
+		if j < 3*peaks {
+			covers = append(covers, row2+j)
+		}
+		slots = append(slots, Slot{
+			Covers: covers,
+			Row:    3,
+			Col:    2 * j,
+		})
+	}
+	return mustLayout(name, slots, peakSlots)
+}
+
+func pyramidLayout() *Layout {
+	const rows = 7
+	slots := make([]Slot, 0, rows*(rows+1)/2)
+	rowStart := 0
+	for r := 0; r < rows; r++ {
+		for i := 0; i <= r; i++ {
+			var covers []int
+			if i > 0 {
+				covers = append(covers, rowStart-r+i-1)
+			}
+			if i < r {
+				covers = append(covers, rowStart-r+i)
+			}
+			slots = append(slots, Slot{
+				Covers: covers,
+				Row:    r,
+				Col:    rows - 1 - r + 2*i,
+			})
+		}
+		rowStart += r + 1
+	}
+	return mustLayout("pyramid", slots, []int{0})
+}
+
+func golfLayout() *Layout {
+	const (
+		columns = 7
+		rows    = 5
+	)
+	slots := make([]Slot, 0, columns*rows)
+	for r := 0; r < rows; r++ {
+		for c := 0; c < columns; c++ {
+			var covers []int
+			if r > 0 {
+				covers = []int{(r-1)*columns + c}
+			}
+			slots = append(slots, Slot{
+				Covers: covers,
+				Row:    r,
+				Col:    2 * c,
+				FaceUp: true,
+			})
+		}
+	}
+	return mustLayout("golf", slots, nil)
+}
@@ -0,0 +1,143 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/MatiasLyyra/TriPeaks/deck"
+)
+
+// Notation returns the game state as a single line of text with five space
+// separated fields:
+//
+//	tableau stock discards score streak
+//
+// Games using some other layout than TriPeaksLayout start with an extra field
+// holding the layout name, and games where the stock has been recycled end
+// with the number of recycles.
+//
+// The tableau lists every slot separated by commas, removed cards are
+// prefixed with '-'. The stock lists the cards from the bottom so that the
+// next card to be drawn is last, or '-' when the stock is empty. The discards
+// are listed in the order they were played. For example
+//
+//	Ah,2s,...,-Kd,9c 4c5d6h 3sQh 2 1
+//
+// Face-down flags and ChildLeft are not stored, they follow from the removed
+// cards. The rules and the move history are not included, ParseNotation uses
+// DefaultRules.
+func (tri *TriPeaks) Notation() string {
+	tableau := make([]string, len(tri.Cards))
+	for i, card := range tri.Cards {
+		if card.Removed {
+			tableau[i] = "-" + card.Short()
+		} else {
+			tableau[i] = card.Short()
+		}
+	}
+	stock := "-"
+	if tri.Stock.Len() > 0 {
+		stock = joinCards(tri.Stock.Cards)
+	}
+	notation := fmt.Sprintf("%s %s %s %d %d",
+		strings.Join(tableau, ","),
+		stock,
+		joinCards(tri.discardHistory()),
+		tri.Score,
+		tri.Streak)
+	if tri.Layout != TriPeaksLayout {
+		notation = tri.Layout.Name + " " + notation
+	}
+	if tri.Recycles > 0 {
+		notation += " " + strconv.Itoa(tri.Recycles)
+	}
+	return notation
+}
+
+// ParseNotation restores a game from the text returned by Notation
+func ParseNotation(notation string) (*TriPeaks, error) {
+	fields := strings.Fields(notation)
+	layout := TriPeaksLayout
+	if len(fields) > 0 {
+		if named, ok := LayoutByName(fields[0]); ok {
+			layout = named
+			fields = fields[1:]
+		}
+	}
+	if len(fields) != 5 && len(fields) != 6 {
+		return nil, fmt.Errorf("notation has %d fields, expected 5 or 6", len(fields))
+	}
+	tri := &TriPeaks{
+		Layout: layout,
+		Rules:  DefaultRules(),
+		Cards:  make(TriPeaksDeck, len(layout.Slots)),
+	}
+	tableau := strings.Split(fields[0], ",")
+	if len(tableau) != len(tri.Cards) {
+		return nil, fmt.Errorf("tableau has %d cards, expected %d", len(tableau), len(tri.Cards))
+	}
+	for i, s := range tableau {
+		removed := strings.HasPrefix(s, "-")
+		card, err := deck.ParseCard(strings.TrimPrefix(s, "-"))
+		if err != nil {
+			return nil, err
+		}
+		tri.Cards[i] = PeakCard{
+			Card:    card,
+			Removed: removed,
+		}
+		if !removed {
+			tri.CardsLeft++
+		}
+	}
+	for pos, card := range tri.Cards {
+		if card.Removed {
+			continue
+		}
+		for _, covered := range layout.Slots[pos].Covers {
+			tri.coverSlot(covered)
+		}
+	}
+
+	if fields[1] != "-" {
+		stock, err := deck.ParseDeck(fields[1])
+		if err != nil {
+			return nil, err
+		}
+		tri.Stock = *stock
+	}
+	discards, err := deck.ParseDeck(fields[2])
+	if err != nil {
+		return nil, err
+	}
+	if discards.Len() == 0 {
+		return nil, errors.New("discard pile is empty")
+	}
+	tri.setDiscardHistory(discards.Cards)
+	if tri.Score, err = strconv.Atoi(fields[3]); err != nil {
+		return nil, fmt.Errorf("invalid score: %s", err)
+	}
+	if tri.Streak, err = strconv.Atoi(fields[4]); err != nil {
+		return nil, fmt.Errorf("invalid streak: %s", err)
+	}
+	if len(fields) == 6 {
+		if tri.Recycles, err = strconv.Atoi(fields[5]); err != nil {
+			return nil, fmt.Errorf("invalid recycle count: %s", err)
+		}
+	}
+	if err := tri.Validate(); err != nil {
+		return nil, err
+	}
+	tri.Rehash()
+	return tri, nil
+}
+
+func joinCards(cards []deck.Card) string {
+	var b strings.Builder
+	for _, card := range cards {
+		b.WriteString(card.Short())
+	}
+	return b.String()
+}
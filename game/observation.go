@@ -0,0 +1,166 @@
+package game
+
+import (
+	"math/rand"
+
+	"github.com/MatiasLyyra/TriPeaks/deck"
+)
+
+// Observation is the part of a TriPeaks game a player is allowed to see.
+// Face-down tableau cards and the stock are replaced with blank cards, so an
+// agent working from an Observation cannot peek at hidden information.
+type Observation struct {
+	tri    *TriPeaks
+	unseen []deck.Card
+}
+
+// Observe returns the player's view of the current game state
+func (tri *TriPeaks) Observe() *Observation {
+	masked := tri.Copy()
+	for i := range masked.Cards {
+		if masked.Cards[i].FaceDown {
+			masked.Cards[i].Card = deck.Card{FaceDown: true}
+		}
+	}
+	if masked.Recycles == 0 {
+		for i := range masked.Stock.Cards {
+			masked.Stock.Cards[i] = deck.Card{FaceDown: true}
+		}
+	}
+	return &Observation{
+		tri:    masked,
+		unseen: unseenCards(tri),
+	}
+}
+
+// Game returns a playable copy of the observed game where every hidden card
+// is blank. Searches can fill the blanks in with determinized cards.
+func (o *Observation) Game() *TriPeaks {
+	return o.tri.Copy()
+}
+
+// Cards returns the tableau, face-down cards have no rank or suit
+func (o *Observation) Cards() TriPeaksDeck {
+	cards := make(TriPeaksDeck, len(o.tri.Cards))
+	copy(cards, o.tri.Cards)
+	return cards
+}
+
+func (o *Observation) Layout() *Layout {
+	return o.tri.Layout
+}
+
+func (o *Observation) Rules() Rules {
+	return o.tri.Rules
+}
+
+// Discard returns the top card of the discard pile
+func (o *Observation) Discard() deck.Card {
+	return o.tri.Discard()
+}
+
+// Discards returns the discard pile in the order the cards were played,
+// the current top card being the last one.
+func (o *Observation) Discards() []deck.Card {
+	return o.tri.discardHistory()
+}
+
+// UnseenCards returns the cards that are either face down on the tableau or
+// in the stock before it has been recycled.
+func (o *Observation) UnseenCards() []deck.Card {
+	cards := make([]deck.Card, len(o.unseen))
+	deck.Copy(cards, o.unseen)
+	return cards
+}
+
+// Determinize returns one of the games the player could be in: the observed
+// game with the hidden cards replaced by a random arrangement of the unseen
+// cards. The cards stay face down and there are no moves to undo.
+func (o *Observation) Determinize(random *rand.Rand) *TriPeaks {
+	tri := &TriPeaks{}
+	o.DeterminizeInto(tri, random)
+	return tri
+}
+
+// DeterminizeInto is Determinize reusing the slices of dst
+func (o *Observation) DeterminizeInto(dst *TriPeaks, random *rand.Rand) {
+	o.tri.CopyStateInto(dst)
+	cards := make([]deck.Card, len(o.unseen))
+	copy(cards, o.unseen)
+	random.Shuffle(len(cards), func(i, j int) {
+		cards[i], cards[j] = cards[j], cards[i]
+	})
+	for i := range dst.Cards {
+		if dst.Cards[i].FaceDown && len(cards) > 0 {
+			dst.Cards[i].Card = cards[len(cards)-1]
+			dst.Cards[i].FaceDown = true
+			cards = cards[:len(cards)-1]
+		}
+	}
+	if dst.Recycles == 0 {
+		for i := range dst.Stock.Cards {
+			if len(cards) > 0 {
+				dst.Stock.Cards[i] = cards[len(cards)-1]
+				cards = cards[:len(cards)-1]
+			}
+		}
+	}
+}
+
+func (o *Observation) StockLen() int {
+	return o.tri.Stock.Len()
+}
+
+// Stock returns the stock if its order is known after turning the discard
+// pile over, the next card to be drawn being last.
+func (o *Observation) Stock() ([]deck.Card, bool) {
+	if o.tri.Recycles == 0 {
+		return nil, false
+	}
+	cards := make([]deck.Card, o.tri.Stock.Len())
+	deck.Copy(cards, o.tri.Stock.Cards)
+	return cards, true
+}
+
+func (o *Observation) CardsLeft() int {
+	return o.tri.CardsLeft
+}
+
+func (o *Observation) Score() int {
+	return o.tri.Score
+}
+
+func (o *Observation) Streak() int {
+	return o.tri.Streak
+}
+
+func (o *Observation) LegalMoves() ([]int, bool) {
+	return o.tri.LegalMoves()
+}
+
+func (o *Observation) GameOver() bool {
+	return o.tri.GameOver()
+}
+
+func (o *Observation) String() string {
+	return o.tri.String()
+}
+
+func unseenCards(tri *TriPeaks) []deck.Card {
+	usedCardsMap := make(map[int]struct{})
+	for _, card := range tri.UsedCards() {
+		usedCardsMap[card.HashCode()] = struct{}{}
+	}
+	if tri.Recycles > 0 {
+		for _, card := range tri.Stock.Cards {
+			usedCardsMap[card.HashCode()] = struct{}{}
+		}
+	}
+	unseen := make([]deck.Card, 0, 52-len(usedCardsMap))
+	for _, card := range deck.New().Cards {
+		if _, contains := usedCardsMap[card.HashCode()]; !contains {
+			unseen = append(unseen, card)
+		}
+	}
+	return unseen
+}
@@ -0,0 +1,81 @@
+package game
+
+import "github.com/MatiasLyyra/TriPeaks/deck"
+
+// Scoring is the table of points awarded and deducted during the game
+type Scoring struct {
+	// CardPoints are given for every card removed from the tableau
+	CardPoints int `json:"cardPoints"`
+	// StreakPoints are multiplied by the position of the card in the
+	// current streak, capped at StreakCap unless it is 0
+	StreakPoints int `json:"streakPoints"`
+	StreakCap    int `json:"streakCap"`
+	// PeakBonus is given for every peak removed and ClearBonus once all
+	// peaks have been removed
+	PeakBonus  int `json:"peakBonus"`
+	ClearBonus int `json:"clearBonus"`
+	// DrawPenalty is deducted for every card drawn from the stock
+	DrawPenalty int `json:"drawPenalty"`
+	// SurrenderPenalty is deducted for every card left when surrendering
+	SurrenderPenalty int `json:"surrenderPenalty"`
+	// UndoPenalty is deducted for every move taken back
+	UndoPenalty int `json:"undoPenalty"`
+}
+
+var (
+	// MicrosoftScoring scores the nth card of a streak n points, a peak 15
+	// points and clearing the peaks 15 more. Draws and cards left on
+	// surrender cost 5 points each.
+	MicrosoftScoring = Scoring{
+		StreakPoints:     1,
+		PeakBonus:        15,
+		ClearBonus:       15,
+		DrawPenalty:      5,
+		SurrenderPenalty: 5,
+	}
+	// PogoScoring gives 100 points per card plus a streak bonus that grows
+	// by 100 for each card up to ten cards, and large bonuses for peaks and
+	// clearing the board. Drawing is free.
+	PogoScoring = Scoring{
+		CardPoints:   100,
+		StreakPoints: 100,
+		StreakCap:    10,
+		PeakBonus:    500,
+		ClearBonus:   5000,
+	}
+)
+
+// Rules are the house rules the game is played with
+type Rules struct {
+	// Wraparound allows playing an Ace on a Two and a Two on an Ace
+	Wraparound bool    `json:"wraparound"`
+	Scoring    Scoring `json:"scoring"`
+	// StockRecycles is how many times the discard pile, except its top card,
+	// can be turned over into a new stock when the stock runs out
+	StockRecycles int `json:"stockRecycles"`
+}
+
+// DefaultRules are the rules of the classic Microsoft Tri Peaks
+func DefaultRules() Rules {
+	return Rules{
+		Wraparound: true,
+		Scoring:    MicrosoftScoring,
+	}
+}
+
+// StreakScore returns the points for removing the nth card of a streak
+func (s Scoring) StreakScore(streak int) int {
+	if s.StreakCap > 0 && streak > s.StreakCap {
+		streak = s.StreakCap
+	}
+	return s.CardPoints + s.StreakPoints*streak
+}
+
+// Playable reports whether the card can be played on the discard, that is
+// whether their ranks are one apart
+func (r Rules) Playable(card, discard deck.Card) bool {
+	return card.Rank-1 == discard.Rank ||
+		card.Rank+1 == discard.Rank ||
+		(r.Wraparound && card.Rank == deck.Two && discard.Rank == deck.Ace) ||
+		(r.Wraparound && card.Rank == deck.Ace && discard.Rank == deck.Two)
+}
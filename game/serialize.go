@@ -0,0 +1,242 @@
+package game
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/MatiasLyyra/TriPeaks/deck"
+)
+
+type peakCardJSON struct {
+	Card      string `json:"card"`
+	Removed   bool   `json:"removed"`
+	FaceDown  bool   `json:"faceDown"`
+	ChildLeft int    `json:"childLeft"`
+}
+
+type triPeaksJSON struct {
+	Layout string         `json:"layout"`
+	Cards  []peakCardJSON `json:"cards"`
+	// Stock is ordered from the bottom, the next card to be drawn is last
+	Stock []string `json:"stock"`
+	// Discards are ordered as they were played, the top card is last
+	Discards  []string `json:"discards"`
+	CardsLeft int      `json:"cardsLeft"`
+	Score     int      `json:"score"`
+	Streak    int      `json:"streak"`
+	Recycles  int      `json:"recycles"`
+	// Rules are optional, DefaultRules are used when missing
+	Rules *Rules `json:"rules,omitempty"`
+}
+
+// MarshalJSON encodes the game state. The move history is not included.
+func (tri *TriPeaks) MarshalJSON() ([]byte, error) {
+	state := triPeaksJSON{
+		Layout:    tri.Layout.Name,
+		Cards:     make([]peakCardJSON, len(tri.Cards)),
+		Stock:     make([]string, len(tri.Stock.Cards)),
+		Discards:  make([]string, 0, len(tri.Discards)),
+		CardsLeft: tri.CardsLeft,
+		Score:     tri.Score,
+		Streak:    tri.Streak,
+		Recycles:  tri.Recycles,
+		Rules:     &tri.Rules,
+	}
+	for i, card := range tri.Cards {
+		state.Cards[i] = peakCardJSON{
+			Card:      card.Short(),
+			Removed:   card.Removed,
+			FaceDown:  card.FaceDown,
+			ChildLeft: card.ChildLeft,
+		}
+	}
+	for i, card := range tri.Stock.Cards {
+		state.Stock[i] = card.Short()
+	}
+	for _, card := range tri.discardHistory() {
+		state.Discards = append(state.Discards, card.Short())
+	}
+	return json.Marshal(state)
+}
+
+// UnmarshalJSON decodes a game encoded with MarshalJSON and validates it
+func (tri *TriPeaks) UnmarshalJSON(data []byte) error {
+	var state triPeaksJSON
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+	layout := TriPeaksLayout
+	if state.Layout != "" {
+		var ok bool
+		if layout, ok = LayoutByName(state.Layout); !ok {
+			return fmt.Errorf("unknown layout %q", state.Layout)
+		}
+	}
+	if len(state.Cards) != len(layout.Slots) {
+		return fmt.Errorf("tableau has %d cards, expected %d", len(state.Cards), len(layout.Slots))
+	}
+	loaded := TriPeaks{
+		Layout:    layout,
+		Rules:     DefaultRules(),
+		Cards:     make(TriPeaksDeck, len(layout.Slots)),
+		CardsLeft: state.CardsLeft,
+		Score:     state.Score,
+		Streak:    state.Streak,
+		Recycles:  state.Recycles,
+	}
+	if state.Rules != nil {
+		loaded.Rules = *state.Rules
+	}
+	for i, pk := range state.Cards {
+		card, err := deck.ParseCard(pk.Card)
+		if err != nil {
+			return err
+		}
+		card.FaceDown = pk.FaceDown
+		loaded.Cards[i] = PeakCard{
+			Card:      card,
+			Removed:   pk.Removed,
+			ChildLeft: pk.ChildLeft,
+		}
+	}
+	stock, err := parseCards(state.Stock)
+	if err != nil {
+		return err
+	}
+	loaded.Stock.Cards = stock
+	discards, err := parseCards(state.Discards)
+	if err != nil {
+		return err
+	}
+	if len(discards) == 0 {
+		return errors.New("discard pile is empty")
+	}
+	loaded.setDiscardHistory(discards)
+	if err := loaded.Validate(); err != nil {
+		return err
+	}
+	loaded.Rehash()
+	*tri = loaded
+	return nil
+}
+
+// Validate checks that the game state is one that can be reached by playing:
+// the cards form a full deck, ChildLeft and face-down flags match the cards
+// still covering each slot and CardsLeft matches the tableau.
+func (tri *TriPeaks) Validate() error {
+	if tri.Layout == nil || len(tri.Layout.Slots) != len(tri.Cards) {
+		return errors.New("tableau does not match the layout")
+	}
+	if len(tri.Discards) == 0 {
+		return errors.New("discard pile is empty")
+	}
+	if tri.Recycles < 0 {
+		return errors.New("negative recycle count")
+	}
+	// Removed tableau cards are also on the discard pile, or in the stock
+	// after recycling, unless the game was surrendered
+	var piles, tableau [52]bool
+	for _, card := range tri.Discards {
+		if err := checkCard(card, &piles); err != nil {
+			return err
+		}
+	}
+	for _, card := range tri.Stock.Cards {
+		if err := checkCard(card, &piles); err != nil {
+			return err
+		}
+	}
+	count := len(tri.Discards) + tri.Stock.Len()
+	for pos, card := range tri.Cards {
+		if err := checkCard(card.Card, &tableau); err != nil {
+			return err
+		}
+		if piles[card.Index()] {
+			if !card.Removed {
+				return fmt.Errorf("card %d is both on the tableau and in the stock or discards", pos)
+			}
+			continue
+		}
+		if card.Removed && tri.CardsLeft != 0 {
+			return fmt.Errorf("card %d is removed but not discarded", pos)
+		}
+		count++
+	}
+	if count != 52 {
+		return fmt.Errorf("game has %d cards, expected 52", count)
+	}
+
+	covering := make([]int, len(tri.Cards))
+	coveringLeft := make([]int, len(tri.Cards))
+	cardsLeft := 0
+	for pos, card := range tri.Cards {
+		for _, covered := range tri.Layout.Slots[pos].Covers {
+			covering[covered]++
+			if !card.Removed {
+				coveringLeft[covered]++
+			}
+		}
+		if !card.Removed {
+			cardsLeft++
+		}
+	}
+	if cardsLeft != tri.CardsLeft {
+		return fmt.Errorf("CardsLeft is %d, but %d cards are on the tableau", tri.CardsLeft, cardsLeft)
+	}
+	for pos, card := range tri.Cards {
+		// Surrendering removes every card without revealing them
+		if card.Removed && cardsLeft == 0 && card.ChildLeft <= covering[pos] {
+			continue
+		}
+		if card.Removed && coveringLeft[pos] > 0 {
+			return fmt.Errorf("card %d is removed while still covered", pos)
+		}
+		if card.ChildLeft != coveringLeft[pos] {
+			return fmt.Errorf("card %d has ChildLeft %d, but is covered by %d cards", pos, card.ChildLeft, coveringLeft[pos])
+		}
+		if card.FaceDown != (card.ChildLeft > 0 && !tri.Layout.Slots[pos].FaceUp) {
+			return fmt.Errorf("card %d has wrong face-down flag", pos)
+		}
+	}
+	return nil
+}
+
+// discardHistory returns the discards in the order they were played
+func (tri *TriPeaks) discardHistory() []deck.Card {
+	history := make([]deck.Card, 0, len(tri.Discards))
+	history = append(history, tri.Discards[1:]...)
+	return append(history, tri.Discards[0])
+}
+
+// setDiscardHistory sets the discard pile from cards in the order they were
+// played
+func (tri *TriPeaks) setDiscardHistory(history []deck.Card) {
+	last := len(history) - 1
+	tri.Discards = make([]deck.Card, 0, len(history))
+	tri.Discards = append(tri.Discards, history[last])
+	tri.Discards = append(tri.Discards, history[:last]...)
+}
+
+func checkCard(card deck.Card, seen *[52]bool) error {
+	if !card.Valid() {
+		return fmt.Errorf("invalid card rank %d suit %d", card.Rank, card.Suit)
+	}
+	if seen[card.Index()] {
+		return fmt.Errorf("duplicate card %s", card.Short())
+	}
+	seen[card.Index()] = true
+	return nil
+}
+
+func parseCards(cards []string) ([]deck.Card, error) {
+	parsed := make([]deck.Card, len(cards))
+	for i, s := range cards {
+		card, err := deck.ParseCard(s)
+		if err != nil {
+			return nil, err
+		}
+		parsed[i] = card
+	}
+	return parsed, nil
+}
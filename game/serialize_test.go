@@ -0,0 +1,235 @@
+package game
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/MatiasLyyra/TriPeaks/deck"
+)
+
+// playedGame deals the layout and plays greedy moves, surrendering at the
+// end if surrender is set
+func playedGame(layout *Layout, rules Rules, moves int, surrender bool) *TriPeaks {
+	stock := deck.New()
+	stock.ShuffleSeed(7)
+	tri := NewTripeaksLayout(*stock, layout, rules)
+	for i := 0; i < moves && !tri.GameOver(); i++ {
+		tri.Play(greedyMove(tri))
+	}
+	if surrender && !tri.GameOver() {
+		tri.Play(Move{Kind: MoveSurrender})
+	}
+	return tri
+}
+
+func serializeCases() []struct {
+	name string
+	tri  *TriPeaks
+} {
+	recycling := DefaultRules()
+	recycling.StockRecycles = 1
+	return []struct {
+		name string
+		tri  *TriPeaks
+	}{
+		{"new game", playedGame(TriPeaksLayout, DefaultRules(), 0, false)},
+		{"played", playedGame(TriPeaksLayout, DefaultRules(), 15, false)},
+		{"game over", playedGame(TriPeaksLayout, DefaultRules(), 200, false)},
+		{"recycled", playedGame(TriPeaksLayout, recycling, 200, false)},
+		{"surrendered", playedGame(TriPeaksLayout, DefaultRules(), 5, true)},
+		{"four peaks", playedGame(FourPeaksLayout, DefaultRules(), 10, false)},
+		{"pyramid", playedGame(PyramidLayout, DefaultRules(), 10, false)},
+		{"golf", playedGame(GolfLayout, DefaultRules(), 10, false)},
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	for _, test := range serializeCases() {
+		t.Run(test.name, func(t *testing.T) {
+			data, err := json.Marshal(test.tri)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var loaded TriPeaks
+			if err := json.Unmarshal(data, &loaded); err != nil {
+				t.Fatalf("failed to load %s: %s", data, err)
+			}
+			if got, want := takeSnapshot(&loaded), takeSnapshot(test.tri); !reflect.DeepEqual(got, want) {
+				t.Fatalf("loaded\n%+v\nwant\n%+v", got, want)
+			}
+			if loaded.Score != test.tri.Score || loaded.Layout != test.tri.Layout || loaded.Rules != test.tri.Rules {
+				t.Fatalf("loaded score %d layout %s rules %+v, want %d %s %+v",
+					loaded.Score, loaded.Layout.Name, loaded.Rules, test.tri.Score, test.tri.Layout.Name, test.tri.Rules)
+			}
+		})
+	}
+}
+
+func TestNotationRoundTrip(t *testing.T) {
+	for _, test := range serializeCases() {
+		t.Run(test.name, func(t *testing.T) {
+			notation := test.tri.Notation()
+			loaded, err := ParseNotation(notation)
+			if err != nil {
+				t.Fatalf("failed to parse %q: %s", notation, err)
+			}
+			// The notation does not store the flags of the removed cards,
+			// which a surrendered game keeps
+			got, want := takeSnapshot(loaded), takeSnapshot(test.tri)
+			for pos, card := range want.Cards {
+				if card.Removed {
+					want.Cards[pos].FaceDown = got.Cards[pos].FaceDown
+					want.Cards[pos].ChildLeft = got.Cards[pos].ChildLeft
+				}
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("parsed\n%+v\nwant\n%+v", got, want)
+			}
+			if loaded.Score != test.tri.Score || loaded.Layout != test.tri.Layout {
+				t.Fatalf("parsed score %d layout %s, want %d %s", loaded.Score, loaded.Layout.Name, test.tri.Score, test.tri.Layout.Name)
+			}
+			if again := loaded.Notation(); again != notation {
+				t.Fatalf("notation changed from %q to %q", notation, again)
+			}
+		})
+	}
+}
+
+func TestValidateRejectsInvalidStates(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(tri *TriPeaks)
+	}{
+		{"duplicate card", func(tri *TriPeaks) { tri.Stock.Cards[0] = tri.Discards[0] }},
+		{"missing card", func(tri *TriPeaks) { tri.Stock.Cards = tri.Stock.Cards[1:] }},
+		{"invalid card", func(tri *TriPeaks) { tri.Stock.Cards[0].Rank = 0 }},
+		{"empty discards", func(tri *TriPeaks) { tri.Discards = nil }},
+		{"cards left", func(tri *TriPeaks) { tri.CardsLeft++ }},
+		{"negative recycles", func(tri *TriPeaks) { tri.Recycles = -1 }},
+		{"tableau size", func(tri *TriPeaks) { tri.Cards = tri.Cards[:len(tri.Cards)-1] }},
+		{"child left", func(tri *TriPeaks) {
+			for pos := range tri.Cards {
+				if tri.Cards[pos].ChildLeft > 0 {
+					tri.Cards[pos].ChildLeft--
+					return
+				}
+			}
+		}},
+		{"face down", func(tri *TriPeaks) {
+			for pos := range tri.Cards {
+				if tri.Cards[pos].FaceDown {
+					tri.Cards[pos].FaceDown = false
+					return
+				}
+			}
+		}},
+		{"removed while covered", func(tri *TriPeaks) {
+			for pos := range tri.Cards {
+				if tri.Cards[pos].ChildLeft > 0 {
+					tri.Cards[pos].Removed = true
+					tri.CardsLeft--
+					return
+				}
+			}
+		}},
+		{"removed and on tableau", func(tri *TriPeaks) {
+			removed, left := -1, -1
+			for pos, card := range tri.Cards {
+				if card.Removed {
+					removed = pos
+				} else {
+					left = pos
+				}
+			}
+			tri.Cards[removed].Card, tri.Cards[left].Card = tri.Cards[left].Card, tri.Cards[removed].Card
+		}},
+	}
+	if err := playedGame(TriPeaksLayout, DefaultRules(), 10, false).Validate(); err != nil {
+		t.Fatalf("valid game rejected: %s", err)
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tri := playedGame(TriPeaksLayout, DefaultRules(), 10, false)
+			test.mutate(tri)
+			if err := tri.Validate(); err == nil {
+				t.Fatalf("invalid game accepted: %s", tri.Notation())
+			}
+		})
+	}
+}
+
+func TestParseNotationRejectsInvalid(t *testing.T) {
+	valid := playedGame(TriPeaksLayout, DefaultRules(), 10, false).Notation()
+	fields := strings.Fields(valid)
+	replace := func(i int, value string) string {
+		changed := append([]string(nil), fields...)
+		changed[i] = value
+		return strings.Join(changed, " ")
+	}
+	tableau := strings.Split(fields[0], ",")
+	tests := []struct {
+		name     string
+		notation string
+	}{
+		{"empty", ""},
+		{"too few fields", strings.Join(fields[:4], " ")},
+		{"too many fields", valid + " 1 2"},
+		{"short tableau", replace(0, strings.Join(tableau[1:], ","))},
+		{"invalid card", replace(0, "Xx,"+strings.Join(tableau[1:], ","))},
+		{"empty discards", replace(2, "")},
+		{"invalid score", replace(3, "x")},
+		{"invalid streak", replace(4, "x")},
+		{"invalid recycles", valid + " x"},
+		{"card missing from the stock", replace(1, fields[1][2:])},
+		{"uncovered card removed", replace(0, "-"+strings.Join(tableau, ","))},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := ParseNotation(test.notation); err == nil {
+				t.Fatalf("invalid notation accepted: %q", test.notation)
+			}
+		})
+	}
+}
+
+func TestUnmarshalRejectsInvalid(t *testing.T) {
+	data, err := json.Marshal(playedGame(TriPeaksLayout, DefaultRules(), 10, false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		mutate func(state map[string]interface{})
+	}{
+		{"unknown layout", func(state map[string]interface{}) { state["layout"] = "fivepeaks" }},
+		{"tableau size", func(state map[string]interface{}) {
+			cards := state["cards"].([]interface{})
+			state["cards"] = cards[1:]
+		}},
+		{"invalid card", func(state map[string]interface{}) {
+			stock := state["stock"].([]interface{})
+			stock[0] = "1z"
+		}},
+		{"empty discards", func(state map[string]interface{}) { state["discards"] = []interface{}{} }},
+		{"cards left", func(state map[string]interface{}) { state["cardsLeft"] = 0.0 }},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var state map[string]interface{}
+			if err := json.Unmarshal(data, &state); err != nil {
+				t.Fatal(err)
+			}
+			test.mutate(state)
+			changed, err := json.Marshal(state)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var tri TriPeaks
+			if err := json.Unmarshal(changed, &tri); err == nil {
+				t.Fatalf("invalid game accepted: %s", changed)
+			}
+		})
+	}
+}
@@ -1,8 +1,6 @@
 package game
 
 import (
-	"fmt"
-
 	"github.com/MatiasLyyra/TriPeaks/deck"
 )
 
@@ -28,18 +26,35 @@ func (c PeakCard) String() string {
 	return c.Card.String()
 }
 
-type TriPeaksDeck [28]PeakCard
+type TriPeaksDeck []PeakCard
 
 type TriPeaks struct {
+	Layout    *Layout
+	Rules     Rules
 	Stock     deck.Deck
 	Discards  []deck.Card
 	Cards     TriPeaksDeck
 	CardsLeft int
 	Score     int
 	Streak    int
+	// Recycles is the number of times the discard pile has been turned
+	// over into a new stock
+	Recycles int
+	hash     uint64
+	// Order hashes of the discard pile and the stock, see State.Order
+	pileOrder  uint64
+	stockOrder uint64
+	history    []undoRecord
+	redo       []undoRecord
+}
+
+// NewTripeaks deals the classic Tri Peaks game
+func NewTripeaks(stock deck.Deck, rules Rules) *TriPeaks {
+	return NewTripeaksLayout(stock, TriPeaksLayout, rules)
 }
 
-func NewTripeaks(stock deck.Deck) *TriPeaks {
+// NewTripeaksLayout deals the game using the given tableau layout
+func NewTripeaksLayout(stock deck.Deck, layout *Layout, rules Rules) *TriPeaks {
 	if stock.Len() != 52 {
 		panic("deck requires 52 cards")
 	}
@@ -47,91 +62,106 @@ func NewTripeaks(stock deck.Deck) *TriPeaks {
 	_, discard := stock.Pop()
 	discard.FaceDown = false
 	game := TriPeaks{
+		Layout:   layout,
+		Rules:    rules,
 		Stock:    stock,
 		Discards: []deck.Card{discard},
+		Cards:    make(TriPeaksDeck, len(layout.Slots)),
 	}
 	for i := 0; i < len(game.Cards); i++ {
 		_, card := game.Stock.Pop()
-
 		cardsLeft++
-		if i >= 18 {
-			game.Cards[i] = PeakCard{
-				Card:      card,
-				Removed:   false,
-				ChildLeft: 0,
-			}
-		} else {
-			card.FaceDown = true
-			game.Cards[i] = PeakCard{
-				Card:      card,
-				Removed:   false,
-				ChildLeft: 2,
-			}
+		card.FaceDown = false
+		game.Cards[i] = PeakCard{
+			Card:    card,
+			Removed: false,
+		}
+	}
+	for pos := range game.Cards {
+		for _, covered := range layout.Slots[pos].Covers {
+			game.coverSlot(covered)
 		}
-
 	}
 	game.CardsLeft = cardsLeft
+	game.Rehash()
 	return &game
 }
 
+// NewTripeaksFromCode deals the game from a deck encoded with deck.Deck.Code
+func NewTripeaksFromCode(code string, rules Rules) (*TriPeaks, error) {
+	stock, err := deck.FromCode(code)
+	if err != nil {
+		return nil, err
+	}
+	return NewTripeaks(*stock, rules), nil
+}
+
 func (tri *TriPeaks) GameOver() bool {
 	legalMoves, _ := tri.LegalMoves()
 	return len(legalMoves) == 0
 }
 
 func (tri *TriPeaks) Copy() *TriPeaks {
-	newTri := &TriPeaks{
-		Discards:  make([]deck.Card, len(tri.Discards)),
-		CardsLeft: tri.CardsLeft,
-		Score:     tri.Score,
-		Streak:    tri.Streak,
-	}
-	deck.Copy(newTri.Discards, tri.Discards)
-	newTri.Stock = *tri.Stock.Copy()
-	for i, card := range tri.Cards {
-		newTri.Cards[i] = card
-	}
+	newTri := &TriPeaks{}
+	tri.CopyInto(newTri)
 	return newTri
 }
-func (tri *TriPeaks) String() string {
-	var gameState string
-	for i := 0; i < 3; i++ {
-		if i == 0 {
-			gameState += fmt.Sprintf("         %s", tri.Cards[i])
-		} else {
-			gameState += fmt.Sprintf("            %s", tri.Cards[i])
-		}
-	}
-	gameState += "\n"
-	for i := 3; i < 9; i++ {
-		if (i-3)%2 == 0 {
-			gameState += fmt.Sprintf("      %s", tri.Cards[i])
-		} else {
-			gameState += fmt.Sprintf("%s", tri.Cards[i])
 
-		}
-	}
-	gameState += "\n   "
-	for i := 9; i < 18; i++ {
-		gameState += fmt.Sprintf("%s", tri.Cards[i])
-	}
-	gameState += "\n"
-	for i := 18; i < 28; i++ {
-		gameState += fmt.Sprintf("%s", tri.Cards[i])
-	}
-	gameState += "\n"
-	return gameState
+// CopyInto makes dst a copy of the game, reusing the slices dst already has
+// to avoid allocating
+func (tri *TriPeaks) CopyInto(dst *TriPeaks) {
+	tri.CopyStateInto(dst)
+	dst.history = append(dst.history, tri.history...)
+	dst.redo = append(dst.redo, tri.redo...)
+}
+
+// CopyStateInto is CopyInto without the moves played and undone, so dst
+// has nothing to undo or redo. Searches use it to copy the position cheaply
+// before every trajectory.
+func (tri *TriPeaks) CopyStateInto(dst *TriPeaks) {
+	dst.Layout = tri.Layout
+	dst.Rules = tri.Rules
+	dst.Stock.Cards = append(dst.Stock.Cards[:0], tri.Stock.Cards...)
+	dst.Discards = append(dst.Discards[:0], tri.Discards...)
+	dst.Cards = append(dst.Cards[:0], tri.Cards...)
+	dst.CardsLeft = tri.CardsLeft
+	dst.Score = tri.Score
+	dst.Streak = tri.Streak
+	dst.Recycles = tri.Recycles
+	dst.hash = tri.hash
+	dst.pileOrder = tri.pileOrder
+	dst.stockOrder = tri.stockOrder
+	dst.history = dst.history[:0]
+	dst.redo = dst.redo[:0]
 }
+
+func (tri *TriPeaks) String() string {
+	return tri.Layout.Render(tri.Cards)
+}
+
 func (tri *TriPeaks) Surrender() {
+	tri.Play(Move{Kind: MoveSurrender})
+}
+
+func (tri *TriPeaks) surrender() []int {
+	removed := make([]int, 0, tri.CardsLeft)
 	for i, card := range tri.Cards {
 		if !card.Removed {
-			tri.Score -= 5
+			tri.Score -= tri.Rules.Scoring.SurrenderPenalty
+			tri.hash ^= zobristRemoved[i]
+			removed = append(removed, i)
 		}
 		tri.Cards[i].Removed = true
 	}
 	tri.CardsLeft = 0
+	return removed
 }
+
 func (tri *TriPeaks) Select(pos int) bool {
+	return tri.Play(Move{Kind: MoveSelect, Pos: pos})
+}
+
+func (tri *TriPeaks) selectCard(pos int) bool {
 	if pos < 0 || pos >= len(tri.Cards) {
 		return false
 	}
@@ -140,22 +170,23 @@ func (tri *TriPeaks) Select(pos int) bool {
 		return false
 	}
 	card.Removed = true
+	tri.hash ^= zobristRemoved[pos]
 	tri.AddDiscard(card.Card)
 	tri.ApplyReveals(pos)
 	tri.CardsLeft--
 	tri.incrementScore()
-	if pos < 3 {
-		tri.Score += 15
-	}
-	if tri.Cards[0].Removed && tri.Cards[1].Removed && tri.Cards[2].Removed {
-		tri.Score += 15
+	if tri.Layout.IsPeak(pos) {
+		tri.Score += tri.Rules.Scoring.PeakBonus
+		if tri.peaksCleared() {
+			tri.Score += tri.Rules.Scoring.ClearBonus
+		}
 	}
 	return true
 }
 
 func (tri *TriPeaks) incrementScore() {
 	tri.Streak += 1
-	tri.Score += tri.Streak
+	tri.Score += tri.Rules.Scoring.StreakScore(tri.Streak)
 }
 
 func (tri *TriPeaks) Discard() deck.Card {
@@ -163,6 +194,8 @@ func (tri *TriPeaks) Discard() deck.Card {
 }
 
 func (tri *TriPeaks) AddDiscard(card deck.Card) {
+	tri.hash ^= zobristCard(tri.Discards[0]) ^ zobristCard(card)
+	tri.pileOrder ^= zobristOrder(&zobristPileOrder, len(tri.Discards), card)
 	temp := tri.Discards[0]
 	tri.Discards = append(tri.Discards, temp)
 	tri.Discards[0] = card
@@ -175,68 +208,25 @@ func (tri *TriPeaks) LegalMoves() ([]int, bool) {
 			legalMoves = append(legalMoves, pos)
 		}
 	}
-	canDraw := tri.Stock.Len() > 0
+	canDraw := tri.Stock.Len() > 0 || tri.canRecycle()
 	if canDraw {
 		legalMoves = append(legalMoves, -1)
 	}
 	return legalMoves, canDraw
 }
 
+// CheckReveals returns the slots that the card at pos covers, -1 if there is
+// no such slot
 func (tri *TriPeaks) CheckReveals(pos int) (int, int) {
 	leftPos := -1
 	rightPos := -1
-	if pos >= 3 && pos < 9 {
-		switch pos {
-		case 3, 4:
-			leftPos = 0
-		case 5, 6:
-			leftPos = 1
-		case 7, 8:
-			leftPos = 2
-		}
-	} else if pos >= 9 && pos < 18 {
-		switch pos {
-		case 9:
-			rightPos = 3
-		case 10:
-			leftPos = 3
-			rightPos = 4
-		case 11:
-			leftPos = 4
-		case 12:
-			rightPos = 5
-		case 13:
-			leftPos = 5
-			rightPos = 6
-		case 14:
-			leftPos = 6
-		case 15:
-			rightPos = 7
-		case 16:
-			leftPos = 7
-			rightPos = 8
-		case 17:
-			leftPos = 8
-		}
-	} else if pos >= 18 && pos < 28 {
-		switch pos {
-		case 18, 19, 20, 21:
-			rightPos = pos - 18 + 9
-			if pos != 18 {
-				leftPos = rightPos - 1
-			}
-		case 22, 23, 24:
-			rightPos = pos - 22 + 13
-			leftPos = rightPos - 1
-		case 25, 26, 27:
-			rightPos = pos - 25 + 16
-			leftPos = rightPos - 1
-			if pos == 27 {
-				rightPos = -1
-			}
-		}
+	covers := tri.Layout.Slots[pos].Covers
+	if len(covers) > 0 {
+		leftPos = covers[0]
+	}
+	if len(covers) > 1 {
+		rightPos = covers[1]
 	}
-
 	return leftPos, rightPos
 }
 
@@ -250,6 +240,24 @@ func (tri *TriPeaks) ApplyReveals(pos int) {
 	}
 }
 
+// coverSlot reverts PeakCard.SubChild, turning the card face down again
+// unless the layout deals it face up
+func (tri *TriPeaks) coverSlot(pos int) {
+	card := &tri.Cards[pos]
+	card.ChildLeft++
+	card.FaceDown = !tri.Layout.Slots[pos].FaceUp
+}
+
+// peaksCleared reports whether every peak of the layout has been removed
+func (tri *TriPeaks) peaksCleared() bool {
+	for _, peak := range tri.Layout.Peaks {
+		if !tri.Cards[peak].Removed {
+			return false
+		}
+	}
+	return len(tri.Layout.Peaks) > 0
+}
+
 func (tri *TriPeaks) UsedCards() []deck.Card {
 	cards := make([]deck.Card, 0, 10)
 	for _, card := range tri.Cards {
@@ -267,18 +275,67 @@ func (tri *TriPeaks) IsLegal(card PeakCard) bool {
 	return !card.FaceDown &&
 		card.ChildLeft == 0 &&
 		!card.Removed &&
-		(card.Rank-1 == tri.Discard().Rank ||
-			card.Rank+1 == tri.Discard().Rank ||
-			(card.Rank == 2 && tri.Discard().Rank == 14) ||
-			(card.Rank == 14 && tri.Discard().Rank == 2))
+		tri.Rules.Playable(card.Card, tri.Discard())
 }
 
 func (tri *TriPeaks) Draw() bool {
+	return tri.Play(Move{Kind: MoveDraw})
+}
+
+func (tri *TriPeaks) draw() bool {
+	if tri.Stock.Len() == 0 && tri.canRecycle() {
+		tri.recycle()
+	}
 	ok, card := tri.Stock.Pop()
 	if ok {
-		tri.Score -= 5
+		tri.hash ^= zobristStock[tri.Stock.Len()+1] ^ zobristStock[tri.Stock.Len()]
+		tri.stockOrder ^= zobristOrder(&zobristStockOrder, tri.Stock.Len(), card)
+		tri.Score -= tri.Rules.Scoring.DrawPenalty
 		tri.Streak = 0
 		tri.AddDiscard(card)
 	}
 	return ok
 }
+
+// StockKnown reports whether the player knows the next card to be drawn.
+// This is the case once the discard pile has been turned over into the stock.
+func (tri *TriPeaks) StockKnown() bool {
+	return tri.Recycles > 0 || tri.Stock.Len() == 0
+}
+
+func (tri *TriPeaks) canRecycle() bool {
+	return tri.Recycles < tri.Rules.StockRecycles && len(tri.Discards) > 1
+}
+
+// recycle turns the discard pile over into a new stock, leaving only the top
+// card on the discard pile. The card discarded first ends up on top of the
+// stock.
+func (tri *TriPeaks) recycle() {
+	history := tri.discardHistory()
+	top := len(history) - 1
+	stock := make([]deck.Card, 0, top)
+	for i := top - 1; i >= 0; i-- {
+		stock = append(stock, history[i])
+	}
+	tri.hash ^= zobristStock[0] ^ zobristStock[len(stock)]
+	tri.hash ^= zobristRecycle(tri.Recycles) ^ zobristRecycle(tri.Recycles+1)
+	tri.Stock.Cards = stock
+	tri.Discards = tri.Discards[:1]
+	tri.Recycles++
+	tri.rehashOrder()
+}
+
+// unrecycle reverts recycle
+func (tri *TriPeaks) unrecycle() {
+	history := make([]deck.Card, 0, tri.Stock.Len()+1)
+	for i := tri.Stock.Len() - 1; i >= 0; i-- {
+		history = append(history, tri.Stock.Cards[i])
+	}
+	history = append(history, tri.Discard())
+	tri.setDiscardHistory(history)
+	tri.hash ^= zobristStock[tri.Stock.Len()] ^ zobristStock[0]
+	tri.hash ^= zobristRecycle(tri.Recycles) ^ zobristRecycle(tri.Recycles-1)
+	tri.Stock.Cards = tri.Stock.Cards[:0]
+	tri.Recycles--
+	tri.rehashOrder()
+}
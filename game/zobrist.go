@@ -0,0 +1,132 @@
+package game
+
+import (
+	"math/rand"
+
+	"github.com/MatiasLyyra/TriPeaks/deck"
+)
+
+// State is the compact form of a position. The tableau cards never move and
+// the stock is only popped from, so for a given deal without stock recycles
+// the removed slots, the stock size and the top discard identify the
+// position. Recycling turns the discard pile over into the stock, so the
+// order of the pile matters while a recycle is left and the order of the
+// stock matters after one. Order covers those.
+type State struct {
+	// Removed has bit n set when slot n has been removed
+	Removed uint64
+	// Stock is the number of cards left in the stock
+	Stock int
+	// Discard is the deck.Card.Index of the top discard
+	Discard  int
+	Recycles int
+	// Order is a hash of the order of the discard pile while the stock can
+	// still be recycled and of the order of the stock once it has been, zero
+	// otherwise
+	Order uint64
+}
+
+var (
+	zobristRemoved  [64]uint64
+	zobristStock    [52]uint64
+	zobristDiscard  [52]uint64
+	zobristRecycles [16]uint64
+	// Keys of a card at a position of the discard pile or of the stock,
+	// indexed by position and deck.Card.Index
+	zobristPileOrder  [52][52]uint64
+	zobristStockOrder [52][52]uint64
+)
+
+func init() {
+	random := rand.New(rand.NewSource(0x7419ea4))
+	tables := [][]uint64{zobristRemoved[:], zobristStock[:], zobristDiscard[:], zobristRecycles[:]}
+	for i := range zobristPileOrder {
+		tables = append(tables, zobristPileOrder[i][:], zobristStockOrder[i][:])
+	}
+	for _, table := range tables {
+		for i := range table {
+			table[i] = random.Uint64()
+		}
+	}
+}
+
+// State returns the compact form of the position
+func (tri *TriPeaks) State() State {
+	state := State{
+		Stock:    tri.Stock.Len(),
+		Discard:  tri.Discard().Index(),
+		Recycles: tri.Recycles,
+		Order:    tri.order(),
+	}
+	for pos, card := range tri.Cards {
+		if card.Removed {
+			state.Removed |= 1 << uint(pos)
+		}
+	}
+	return state
+}
+
+// Hash returns the 64-bit Zobrist hash of State. It is updated incrementally
+// by the moves and by Undo, call Rehash after changing the fields of the game
+// directly.
+func (tri *TriPeaks) Hash() uint64 {
+	return tri.hash ^ tri.order()
+}
+
+// order returns State.Order from the order hashes kept by the moves
+func (tri *TriPeaks) order() uint64 {
+	var order uint64
+	if tri.Recycles < tri.Rules.StockRecycles {
+		order ^= tri.pileOrder
+	}
+	if tri.Recycles > 0 {
+		order ^= tri.stockOrder
+	}
+	return order
+}
+
+// Rehash computes the hash of the game from scratch
+func (tri *TriPeaks) Rehash() {
+	tri.hash = zobristStock[tri.Stock.Len()] ^ zobristRecycle(tri.Recycles)
+	if len(tri.Discards) > 0 {
+		tri.hash ^= zobristCard(tri.Discard())
+	}
+	for pos, card := range tri.Cards {
+		if card.Removed {
+			tri.hash ^= zobristRemoved[pos]
+		}
+	}
+	tri.rehashOrder()
+}
+
+// rehashOrder computes the order hashes of the discard pile and the stock
+// from scratch
+func (tri *TriPeaks) rehashOrder() {
+	tri.pileOrder = 0
+	for pos, card := range tri.discardHistory() {
+		tri.pileOrder ^= zobristOrder(&zobristPileOrder, pos, card)
+	}
+	tri.stockOrder = 0
+	for pos, card := range tri.Stock.Cards {
+		tri.stockOrder ^= zobristOrder(&zobristStockOrder, pos, card)
+	}
+}
+
+func zobristCard(card deck.Card) uint64 {
+	// Blank cards of an Observation have no key
+	if !card.Valid() {
+		return 0
+	}
+	return zobristDiscard[card.Index()]
+}
+
+func zobristOrder(table *[52][52]uint64, pos int, card deck.Card) uint64 {
+	if !card.Valid() {
+		return 0
+	}
+	return table[pos][card.Index()]
+}
+
+func zobristRecycle(recycles int) uint64 {
+	return zobristRecycles[recycles%len(zobristRecycles)]
+}
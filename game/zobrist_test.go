@@ -0,0 +1,56 @@
+package game
+
+import (
+	"testing"
+
+	"github.com/MatiasLyyra/TriPeaks/deck"
+)
+
+// The order of the discard pile matters while the stock can be recycled, so
+// positions reached by playing the same cards in another order must differ
+func TestStateKeepsPileOrder(t *testing.T) {
+	for _, recycles := range []int{0, 1} {
+		rules := DefaultRules()
+		rules.StockRecycles = recycles
+		stock := deck.New()
+		stock.ShuffleSeed(1)
+		tri := NewTripeaks(*stock, rules)
+		type seen struct {
+			state State
+			hash  uint64
+			pile  string
+		}
+		positions := make(map[State]seen)
+		reordered := 0
+		var walk func(depth int)
+		walk = func(depth int) {
+			state := tri.State()
+			position := seen{state, tri.Hash(), joinCards(tri.discardHistory())}
+			unordered := state
+			unordered.Order = 0
+			if recycles == 0 && state.Order != 0 {
+				t.Fatalf("order %x without recycles", state.Order)
+			}
+			if other, exists := positions[unordered]; exists && other.pile != position.pile {
+				reordered++
+				if recycles > 0 && (other.state == state || other.hash == position.hash) {
+					t.Fatalf("discard piles %s and %s have the same state", other.pile, position.pile)
+				}
+			}
+			positions[unordered] = position
+			if depth == 0 {
+				return
+			}
+			moves, _ := tri.LegalMoves()
+			for _, pos := range moves {
+				tri.Play(MoveFromPos(pos))
+				walk(depth - 1)
+				tri.Undo()
+			}
+		}
+		walk(10)
+		if reordered == 0 {
+			t.Fatalf("no position was reached with the discard pile in another order")
+		}
+	}
+}
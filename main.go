@@ -1,71 +1,166 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"log"
+	"os"
+	"path/filepath"
 	"runtime"
+	"time"
 
+	"github.com/MatiasLyyra/TriPeaks/agent"
 	"github.com/MatiasLyyra/TriPeaks/deck"
 	"github.com/MatiasLyyra/TriPeaks/game"
 	"github.com/MatiasLyyra/TriPeaks/mcts"
 )
 
 func main() {
+	seed := flag.Uint64("seed", 0, "seed used to shuffle the deck, random if 0")
+	deal := flag.String("deal", "", "deal code of the game to play, overrides -seed")
+	think := flag.Duration("think", 0, "time the AI thinks per move, fixed number of trajectories if 0")
+	reuse := flag.Bool("reuse", false, "continue the AI's search from the subtree of the move played")
+	final := flag.String("final", "score", "how the AI picks its move: score, visits, mean, secure or softmax")
+	ismcts := flag.Bool("ismcts", false, "search with single observer information set MCTS")
+	playerName := flag.String("player", "mcts", "who plays the game: mcts, random or human")
+	dot := flag.String("dot", "", "directory to write the AI's first search tree of every move to in the DOT format")
+	dotDepth := flag.Int("dot-depth", 3, "depth of the trees written with -dot, whole tree if 0")
+	dotVisits := flag.Int("dot-visits", 10, "leave out the nodes with fewer visits from the trees written with -dot")
+	flag.Parse()
+
 	threads := runtime.NumCPU()
 	runtime.GOMAXPROCS(threads)
-	deck := deck.New()
-	deck.Shuffle()
-	game := game.NewTripeaks(*deck)
-	determinizations := 72 / threads
-	trajectories := 5000
-	fmt.Printf("Running %d determinizations wtih %d trajectories using %d cores\n", determinizations, trajectories, threads)
-	for {
-		legalMoves, _ := game.LegalMoves()
-		fmt.Printf("%s", game)
-		fmt.Printf("Cards in deck: %d\tScore: %d\t\tDiscard: %s\n", game.Stock.Len(), game.Score, game.Discard())
-		if game.CardsLeft == 0 {
-			fmt.Printf("AI won the game!\n")
+	stock := deck.New()
+	if *deal != "" {
+		var err error
+		stock, err = deck.FromCode(*deal)
+		if err != nil {
+			log.Fatalf("invalid deal: %s", err)
+		}
+	} else {
+		if *seed == 0 {
+			*seed = uint64(time.Now().UTC().UnixNano())
+		}
+		stock.ShuffleSeed(*seed)
+	}
+	code, err := stock.Code()
+	if err != nil {
+		log.Fatalf("invalid deck: %s", err)
+	}
+	fmt.Printf("Deal: %s\n", code)
+	tri := game.NewTripeaks(*stock, game.DefaultRules())
+	var (
+		player agent.Player
+		ai     *agent.MCTS
+	)
+	switch *playerName {
+	case "mcts":
+		determinizations := 72 / threads
+		if determinizations < 1 {
+			determinizations = 1
+		}
+		trajectories := 5000
+		ai = agent.NewMCTS(threads, determinizations, trajectories, mcts.ScoreSigmoidEval)
+		if *think > 0 {
+			fmt.Printf("Thinking %s per move with %d trajectories per determinization using %d cores\n", *think, trajectories, threads)
+			ai.ThinkTime = *think
+		} else {
+			fmt.Printf("Running %d determinizations wtih %d trajectories using %d cores\n", determinizations, trajectories, threads)
+		}
+		ai.Out = os.Stdout
+		ai.ReuseTree = *reuse
+		if *ismcts {
+			ai.Mode = mcts.ISMCTS
+		}
+		switch *final {
+		case "score":
+			ai.Final = mcts.MaxScore{}
+		case "visits":
+			ai.Final = mcts.MaxVisits{}
+		case "mean":
+			ai.Final = mcts.MaxMean{}
+		case "secure":
+			ai.Final = mcts.SecureChild{A: 1}
+		case "softmax":
+			ai.Final = mcts.Softmax{Temperature: 0.05}
+		default:
+			log.Fatalf("unknown final move policy %q", *final)
+		}
+		player = ai
+	case "random":
+		player = &agent.Random{}
+	case "human":
+		player = agent.NewHuman(os.Stdin, os.Stdout)
+	default:
+		log.Fatalf("unknown player %q", *playerName)
+	}
+	who := "AI"
+	if *playerName == "human" {
+		who = "You"
+	}
+	for turn := 1; ; turn++ {
+		legalMoves, _ := tri.LegalMoves()
+		fmt.Printf("%s", tri)
+		fmt.Printf("Cards in deck: %d\tScore: %d\t\tDiscard: %s\n", tri.Stock.Len(), tri.Score, tri.Discard())
+		if tri.CardsLeft == 0 {
+			fmt.Printf("%s won the game!\n", who)
 			break
 		} else if len(legalMoves) == 0 {
-			fmt.Printf("AI lost the game :(\n")
+			fmt.Printf("%s lost the game :(\n", who)
 			break
 		}
 
-		movesMap := make(map[int]float64)
-		movesChan := make(chan []mcts.SearchResult, 2)
-		for i := 0; i < threads; i++ {
-			go func() {
-				movesChan <- mcts.Search(game, determinizations, trajectories, mcts.ScoreSigmoidEval)
-			}()
+		obs := tri.Observe()
+		move := player.Move(obs)
+		if ai != nil {
+			fmt.Println(ai.Stats())
 		}
-		highestScore := -1.0
-		action := -1
-		i := 0
-		for moves := range movesChan {
-			for _, move := range moves {
-				if _, exists := movesMap[move.Move]; exists {
-					movesMap[move.Move] += move.Score
-				} else {
-					movesMap[move.Move] = move.Score
-				}
-				if movesMap[move.Move] > highestScore {
-					highestScore = movesMap[move.Move]
-					action = move.Move
-				}
+		if ai != nil && *dot != "" {
+			options := mcts.DotOptions{
+				Depth:     *dotDepth,
+				MinVisits: *dotVisits,
+				Game:      obs.Game(),
 			}
-			i++
-			if i >= threads {
-				break
+			switch move.Kind {
+			case game.MoveDraw:
+				options.Path = []int{-1}
+			case game.MoveSelect:
+				options.Path = []int{move.Pos}
 			}
+			writeDot(*dot, turn, ai.Roots(), options)
 		}
-		for move, score := range movesMap {
-			fmt.Printf("Move %d Score %f\n", move, score/float64(determinizations*threads*trajectories))
+		switch move.Kind {
+		case game.MoveDraw:
+			fmt.Printf("%s chose to draw a card\n", who)
+		case game.MoveSelect:
+			fmt.Printf("%s chose to discard %s on position: %d\n", who, tri.Cards[move.Pos], move.Pos)
+		case game.MoveSurrender:
+			fmt.Printf("%s surrendered with %d cards left\n", who, tri.CardsLeft)
 		}
-		if action == -1 {
-			fmt.Printf("AI Chose to draw a card\n")
-			game.Draw()
-		} else {
-			fmt.Printf("AI Chose to discard %s on position: %d\n", game.Cards[action], action)
-			game.Select(action)
+		if !tri.Play(move) {
+			log.Fatalf("illegal move: %s", move)
+		}
+		if move.Kind == game.MoveSurrender {
+			fmt.Printf("Final score: %d\n", tri.Score)
+			break
 		}
 	}
 }
+
+// writeDot writes the first tree of the search to dir, nothing is written for
+// moves that were not searched
+func writeDot(dir string, turn int, roots []*mcts.Node, options mcts.DotOptions) {
+	if len(roots) == 0 {
+		return
+	}
+	path := filepath.Join(dir, fmt.Sprintf("move%03d.dot", turn))
+	f, err := os.Create(path)
+	if err != nil {
+		log.Printf("failed to create %s: %s", path, err)
+		return
+	}
+	defer f.Close()
+	if err := mcts.WriteDot(f, roots[0], options); err != nil {
+		log.Printf("failed to write %s: %s", path, err)
+	}
+}
@@ -0,0 +1,170 @@
+package mcts
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/awalterschulze/gographviz"
+
+	"github.com/MatiasLyyra/TriPeaks/game"
+)
+
+// DotOptions controls which part of a tree WriteDot exports
+type DotOptions struct {
+	// Depth is the deepest level of nodes exported, the children of the root
+	// being on level 1. The whole tree is exported if 0.
+	Depth int
+	// MinVisits leaves out the nodes visited fewer times and their subtrees
+	MinVisits int
+	// C is the exploration constant of the UCB scores, sqrt(2) if 0
+	C float64
+	// Game is the position of the root, usually the game of the observation
+	// that was searched. With it the nodes are labelled with the cards they
+	// play, without it only with their slots.
+	Game *game.TriPeaks
+	// Path are the moves highlighted from the root, usually the move that
+	// was played. After them the path follows the children with the highest
+	// summed reward.
+	Path []int
+}
+
+// WriteDot writes the tree under root in the Graphviz DOT format. Every node
+// is labelled with its move, the cards it determinized, its visits, mean
+// reward and UCB score.
+func WriteDot(w io.Writer, root *Node, options DotOptions) error {
+	if options.C == 0 {
+		options.C = math.Sqrt2
+	}
+	d := &dotWriter{
+		graph:   gographviz.NewGraph(),
+		options: options,
+	}
+	if err := d.graph.SetName("tree"); err != nil {
+		return err
+	}
+	if err := d.graph.SetDir(true); err != nil {
+		return err
+	}
+	var tri *game.TriPeaks
+	if options.Game != nil {
+		tri = options.Game.Copy()
+	}
+	if _, err := d.addNode(root, tri, 0, true); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, d.graph.String())
+	return err
+}
+
+type dotWriter struct {
+	graph   *gographviz.Graph
+	options DotOptions
+	nodes   int
+}
+
+// addNode adds the node and its subtree, tri is the position after the move
+// of the node or nil. Returns the name of the node.
+func (d *dotWriter) addNode(node *Node, tri *game.TriPeaks, depth int, highlighted bool) (string, error) {
+	name := fmt.Sprintf("n%d", d.nodes)
+	d.nodes++
+	attrs := map[string]string{
+		"shape": "box",
+		"label": strconv.Quote(d.label(node, tri, depth == 0)),
+	}
+	if highlighted {
+		attrs["color"] = "red"
+		attrs["penwidth"] = "2"
+	}
+	if err := d.graph.AddNode("tree", name, attrs); err != nil {
+		return "", err
+	}
+	if d.options.Depth > 0 && depth >= d.options.Depth {
+		return name, nil
+	}
+	next := d.nextOnPath(node, depth)
+	for _, child := range node.Children {
+		if child.N < d.options.MinVisits {
+			continue
+		}
+		// The cards are unknown below moves the game cannot replay, such as
+		// the cards of an ISMCTS tree revealed by the determinizations
+		var childGame *game.TriPeaks
+		if tri != nil && (child.Pos == -1 || tri.IsLegal(tri.Cards[child.Pos])) {
+			childGame = tri.Copy()
+			applyNode(childGame, child, &NodeData{})
+		}
+		onPath := highlighted && child == next
+		childName, err := d.addNode(child, childGame, depth+1, onPath)
+		if err != nil {
+			return "", err
+		}
+		edgeAttrs := map[string]string{}
+		if onPath {
+			edgeAttrs["color"] = "red"
+			edgeAttrs["penwidth"] = "2"
+		}
+		if err := d.graph.AddEdge(name, childName, true, edgeAttrs); err != nil {
+			return "", err
+		}
+	}
+	return name, nil
+}
+
+// nextOnPath returns the child on the highlighted path
+func (d *dotWriter) nextOnPath(node *Node, depth int) *Node {
+	if depth < len(d.options.Path) {
+		pos := node.ChildPos(d.options.Path[depth])
+		if pos == -1 {
+			return nil
+		}
+		return node.Children[pos]
+	}
+	return argMax(node.Children, func(child *Node) float64 {
+		_, reward, _ := child.Stats()
+		return reward
+	})
+}
+
+func (d *dotWriter) label(node *Node, tri *game.TriPeaks, root bool) string {
+	visits, _, _ := node.Stats()
+	var lines []string
+	if root {
+		lines = append(lines, "root")
+	} else {
+		lines = append(lines, moveLabel(node, tri))
+		var dealt []string
+		for _, det := range []Deter{node.LeftDet, node.RightDet} {
+			if !det.Initialized {
+				continue
+			}
+			if node.Pos == -1 {
+				dealt = append(dealt, det.Card.Short())
+			} else {
+				dealt = append(dealt, fmt.Sprintf("%s at %d", det.Card.Short(), det.Pos))
+			}
+		}
+		if len(dealt) > 0 {
+			lines = append(lines, "dealt "+strings.Join(dealt, ", "))
+		}
+	}
+	lines = append(lines, fmt.Sprintf("N %d mean %.3f", visits, node.Mean()))
+	if !root && node.N > 0 {
+		ucb := node.Mean() + d.options.C*math.Sqrt(math.Log(float64(node.Parent.N))/float64(node.N))
+		lines = append(lines, fmt.Sprintf("UCB %.3f", ucb))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// moveLabel names the move of the node, tri is the position after it
+func moveLabel(node *Node, tri *game.TriPeaks) string {
+	if node.Pos == -1 {
+		return "draw"
+	}
+	if tri == nil {
+		return fmt.Sprintf("select %d", node.Pos)
+	}
+	return fmt.Sprintf("select %d %s", node.Pos, tri.Cards[node.Pos].Card.Short())
+}
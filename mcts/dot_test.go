@@ -0,0 +1,101 @@
+package mcts
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/awalterschulze/gographviz"
+)
+
+// tinyTree returns a root with a drawing child that has a child of its own,
+// a card child and a card child visited once
+func tinyTree() *Node {
+	root := newParent(10,
+		&Node{N: 6, X: 3},
+		&Node{N: 3, X: 2},
+		&Node{N: 1, X: 1},
+	)
+	root.Children[0].Pos = -1
+	draw := root.Children[0]
+	grandchild := &Node{Pos: 4, Parent: draw, N: 4, X: 2}
+	draw.Children = []*Node{grandchild}
+	return root
+}
+
+func TestWriteDot(t *testing.T) {
+	tests := []struct {
+		name  string
+		opts  DotOptions
+		nodes int
+		edges int
+		red   int
+	}{
+		{"whole tree", DotOptions{}, 5, 4, 3},
+		{"depth", DotOptions{Depth: 1}, 4, 3, 2},
+		{"min visits", DotOptions{MinVisits: 2}, 4, 3, 3},
+		// The path goes to the card and ends there
+		{"path", DotOptions{Path: []int{1}}, 5, 4, 2},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := WriteDot(&buf, tinyTree(), test.opts); err != nil {
+				t.Fatal(err)
+			}
+			graph, err := gographviz.Read(buf.Bytes())
+			if err != nil {
+				t.Fatalf("output does not parse: %s\n%s", err, buf.String())
+			}
+			if len(graph.Nodes.Nodes) != test.nodes || len(graph.Edges.Edges) != test.edges {
+				t.Fatalf("%d nodes and %d edges, want %d and %d\n%s",
+					len(graph.Nodes.Nodes), len(graph.Edges.Edges), test.nodes, test.edges, buf.String())
+			}
+			red := 0
+			for _, node := range graph.Nodes.Nodes {
+				if node.Attrs["color"] == "red" {
+					red++
+				}
+			}
+			if red != test.red {
+				t.Fatalf("%d highlighted nodes, want %d\n%s", red, test.red, buf.String())
+			}
+		})
+	}
+}
+
+// countNodes returns the nodes of the tree down to depth
+func countNodes(node *Node, depth int) int {
+	count := 1
+	if depth > 0 {
+		for _, child := range node.Children {
+			count += countNodes(child, depth-1)
+		}
+	}
+	return count
+}
+
+func TestWriteDotOfSearch(t *testing.T) {
+	obs := newGame(3).Observe()
+	searcher := NewSearcher(Options{
+		Determinizations: 1,
+		Trajectories:     200,
+		Eval:             ScoreSigmoidEval,
+		Seed:             1,
+	})
+	searcher.Search(context.Background(), obs)
+	root := searcher.Roots()[0]
+	var buf bytes.Buffer
+	if err := WriteDot(&buf, root, DotOptions{Depth: 2, Game: obs.Game()}); err != nil {
+		t.Fatal(err)
+	}
+	graph, err := gographviz.Read(buf.Bytes())
+	if err != nil {
+		t.Fatalf("output does not parse: %s\n%s", err, buf.String())
+	}
+	nodes := countNodes(root, 2)
+	if len(graph.Nodes.Nodes) != nodes || len(graph.Edges.Edges) != nodes-1 {
+		t.Fatalf("%d nodes and %d edges, want %d and %d",
+			len(graph.Nodes.Nodes), len(graph.Edges.Edges), nodes, nodes-1)
+	}
+}
@@ -0,0 +1,115 @@
+package mcts
+
+import (
+	"math"
+	"math/rand"
+)
+
+// FinalPolicy chooses the move to play from the results of a search. Random
+// is only used by policies that sample. Ok is false if there are no results
+// to choose from.
+type FinalPolicy interface {
+	Choose(results SearchResults, random *rand.Rand) (move int, ok bool)
+}
+
+// MaxScore picks the move with the highest summed reward
+type MaxScore struct{}
+
+func (p MaxScore) Choose(results SearchResults, random *rand.Rand) (int, bool) {
+	return chooseMax(results, func(r SearchResult) float64 {
+		return r.Score
+	})
+}
+
+// MaxVisits picks the most visited move, also known as the robust child
+type MaxVisits struct{}
+
+func (p MaxVisits) Choose(results SearchResults, random *rand.Rand) (int, bool) {
+	return chooseMax(results, func(r SearchResult) float64 {
+		return float64(r.Visits)
+	})
+}
+
+// MaxMean picks the move with the highest mean reward
+type MaxMean struct{}
+
+func (p MaxMean) Choose(results SearchResults, random *rand.Rand) (int, bool) {
+	return chooseMax(results, func(r SearchResult) float64 {
+		return r.Mean
+	})
+}
+
+// SecureChild picks the move with the highest lower confidence bound
+// mean - A / sqrt(visits), which avoids moves with a high mean from only a
+// few visits
+type SecureChild struct {
+	A float64
+}
+
+func (p SecureChild) Choose(results SearchResults, random *rand.Rand) (int, bool) {
+	return chooseMax(results, func(r SearchResult) float64 {
+		if r.Visits == 0 {
+			return math.Inf(-1)
+		}
+		return r.Mean - p.A/math.Sqrt(float64(r.Visits))
+	})
+}
+
+// Softmax samples a move with probability proportional to
+// e^(mean / Temperature). A Temperature of 0 picks the highest mean.
+type Softmax struct {
+	Temperature float64
+}
+
+func (p Softmax) Choose(results SearchResults, random *rand.Rand) (int, bool) {
+	if p.Temperature <= 0 || len(results) == 0 {
+		return MaxMean{}.Choose(results, random)
+	}
+	// Shifted by the highest mean so that the exponents cannot overflow
+	highest := math.Inf(-1)
+	for _, r := range results {
+		highest = math.Max(highest, r.Mean)
+	}
+	weights := make([]float64, len(results))
+	sum := 0.0
+	for i, r := range results {
+		weights[i] = math.Exp((r.Mean - highest) / p.Temperature)
+		sum += weights[i]
+	}
+	x := random.Float64() * sum
+	for i, w := range weights {
+		x -= w
+		if x < 0 {
+			return results[i].Move, true
+		}
+	}
+	return results[len(results)-1].Move, true
+}
+
+// chooseMax returns the move with the highest value, ties broken by the
+// visits and then the mean. Drawing is only chosen if its value is strictly
+// higher than that of every card, equal values usually mean that the search
+// could not tell the moves apart. Ok is false if there are no results.
+func chooseMax(results SearchResults, value func(SearchResult) float64) (int, bool) {
+	var (
+		best    SearchResult
+		highest float64
+		found   bool
+		draw    *SearchResult
+	)
+	for i, r := range results {
+		if r.Move == -1 {
+			draw = &results[i]
+			continue
+		}
+		v := value(r)
+		if !found || v > highest ||
+			v == highest && (r.Visits > best.Visits || r.Visits == best.Visits && r.Mean > best.Mean) {
+			best, highest, found = r, v, true
+		}
+	}
+	if draw != nil && (!found || value(*draw) > highest) {
+		return draw.Move, true
+	}
+	return best.Move, found
+}
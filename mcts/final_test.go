@@ -0,0 +1,93 @@
+package mcts
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestFinalPolicies(t *testing.T) {
+	// Move 3 has the highest score and visits, move 5 the highest mean from
+	// a few visits
+	results := SearchResults{
+		{Move: -1, Score: 20, Visits: 50, Mean: 0.4},
+		{Move: 3, Score: 60, Visits: 100, Mean: 0.6},
+		{Move: 5, Score: 9, Visits: 10, Mean: 0.9},
+	}
+	tests := []struct {
+		name    string
+		policy  FinalPolicy
+		results SearchResults
+		want    int
+		ok      bool
+	}{
+		{"max score", MaxScore{}, results, 3, true},
+		{"max visits", MaxVisits{}, results, 3, true},
+		{"max mean", MaxMean{}, results, 5, true},
+		{"secure child", SecureChild{A: 2}, results, 3, true},
+		{"secure child without uncertainty", SecureChild{A: 0}, results, 5, true},
+		{"softmax without temperature", Softmax{}, results, 5, true},
+		{"no results", MaxScore{}, nil, 0, false},
+		{"no results with softmax", Softmax{Temperature: 1}, nil, 0, false},
+		{
+			"secure child skips unvisited",
+			SecureChild{A: 1},
+			SearchResults{{Move: 2}, {Move: 4, Visits: 1, Mean: 0.1}},
+			4, true,
+		},
+		{
+			"ties broken by visits",
+			MaxMean{},
+			SearchResults{{Move: 2, Visits: 10, Mean: 0.5}, {Move: 4, Visits: 20, Mean: 0.5}},
+			4, true,
+		},
+		{
+			"ties broken by mean",
+			MaxVisits{},
+			SearchResults{{Move: 2, Visits: 10, Mean: 0.7}, {Move: 4, Visits: 10, Mean: 0.5}},
+			2, true,
+		},
+		{
+			"card preferred over drawing on ties",
+			MaxScore{},
+			SearchResults{{Move: -1, Score: 5, Visits: 20, Mean: 0.5}, {Move: 7, Score: 5, Visits: 10, Mean: 0.5}},
+			7, true,
+		},
+		{
+			"drawing when it is strictly better",
+			MaxScore{},
+			SearchResults{{Move: -1, Score: 6, Visits: 10, Mean: 0.6}, {Move: 7, Score: 5, Visits: 10, Mean: 0.5}},
+			-1, true,
+		},
+		{
+			"drawing as the only move",
+			MaxVisits{},
+			SearchResults{{Move: -1, Visits: 10}},
+			-1, true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			move, ok := test.policy.Choose(test.results, rand.New(rand.NewSource(1)))
+			if ok != test.ok || ok && move != test.want {
+				t.Fatalf("chose %d, %v, want %d, %v", move, ok, test.want, test.ok)
+			}
+		})
+	}
+}
+
+func TestSoftmaxSamplesByMean(t *testing.T) {
+	results := SearchResults{
+		{Move: 1, Visits: 10, Mean: 0.2},
+		{Move: 2, Visits: 10, Mean: 0.8},
+	}
+	random := rand.New(rand.NewSource(1))
+	counts := make(map[int]int)
+	for i := 0; i < 1000; i++ {
+		move, _ := Softmax{Temperature: 0.3}.Choose(results, random)
+		counts[move]++
+	}
+	// e^(0.6 / 0.3) = 7.4 times as likely
+	if counts[2] < 800 || counts[2] > 950 || counts[1] == 0 {
+		t.Fatalf("chose the moves %v times", counts)
+	}
+}
@@ -0,0 +1,108 @@
+package mcts
+
+import (
+	"time"
+
+	"github.com/MatiasLyyra/TriPeaks/game"
+)
+
+// SearchMode selects the search algorithm
+type SearchMode int
+
+const (
+	// Determinized builds a tree for each determinization and deals the
+	// hidden cards lazily as they are revealed, the search of "determinization
+	// and independent futures"
+	Determinized SearchMode = iota
+	// ISMCTS is single observer Information Set MCTS. All determinizations
+	// share one tree over the moves of the player and every trajectory
+	// samples a new determinization from the observation. Trajectories is
+	// then the number of trajectories per determinization the search would
+	// have run, the tree gets Determinizations * Trajectories of them.
+	ISMCTS
+)
+
+func (m SearchMode) String() string {
+	switch m {
+	case Determinized:
+		return "determinized"
+	case ISMCTS:
+		return "ismcts"
+	}
+	return "unknown"
+}
+
+// ismctsTrajectory runs one iteration of SO-ISMCTS. Only the children whose
+// moves are legal in the sampled determinization can be selected, and they
+// are chosen by the AvailabilityPolicy counting how often each child was
+// available instead of how often the parent was visited. The play-out after
+// the expanded node is not added to the tree.
+func (w *worker) ismctsTrajectory(t *tree) {
+	start := time.Now()
+	w.obs.DeterminizeInto(w.game, w.random)
+	t.lock()
+	node := t.root
+	t.addVirtualLoss(node, false)
+	for !w.game.GameOver() {
+		moves, _ := w.game.LegalMoves()
+		untried := untriedMoves(node, moves)
+		if len(untried) > 0 {
+			child := NewNode()
+			child.Pos = w.rollout.Choose(w.game, untried, w.random)
+			child.Parent = node
+			child.Avail = 1
+			node.Children = append(node.Children, child)
+			node = child
+			w.counters.nodes++
+		} else {
+			node = w.selectAvailable(node, moves)
+		}
+		w.game.Apply(game.MoveFromPos(node.Pos))
+		t.addVirtualLoss(node, false)
+		if len(untried) > 0 {
+			break
+		}
+	}
+	t.unlock()
+	// The selection ends at the expanded node
+	expanded := time.Now()
+	rollout := 0
+	for !w.game.GameOver() {
+		moves, _ := w.game.LegalMoves()
+		w.game.Apply(game.MoveFromPos(w.rollout.Choose(w.game, moves, w.random)))
+		rollout++
+	}
+	reward := w.eval(node, w.game)
+	simulated := time.Now()
+	t.lock()
+	t.backpropagate(node, reward)
+	t.unlock()
+	w.counters.selection += expanded.Sub(start)
+	w.counters.simulation += simulated.Sub(expanded)
+	w.counters.backprop += time.Since(simulated)
+	w.counters.addTrajectory(node, rollout)
+}
+
+// untriedMoves returns the moves that have no child yet
+func untriedMoves(node *Node, moves []int) []int {
+	untried := make([]int, 0, len(moves))
+	for _, move := range moves {
+		if node.ChildPos(move) == -1 {
+			untried = append(untried, move)
+		}
+	}
+	return untried
+}
+
+// selectAvailable counts the children whose moves are legal as available and
+// selects one of them with the policy
+func (w *worker) selectAvailable(node *Node, moves []int) *Node {
+	available := w.available[:0]
+	for _, move := range moves {
+		child := node.Children[node.ChildPos(move)]
+		child.Avail++
+		available = append(available, child)
+	}
+	w.available = available
+	return w.policy.(AvailabilityPolicy).SelectAvailable(node, available, w.game, w.random)
+}
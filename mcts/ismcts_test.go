@@ -0,0 +1,124 @@
+package mcts
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+
+	"github.com/MatiasLyyra/TriPeaks/game"
+)
+
+func TestISMCTSWithEveryPolicy(t *testing.T) {
+	obs := newGame(4).Observe()
+	legal, _ := obs.LegalMoves()
+	policies := []SelectionPolicy{
+		UCB1{C: 1},
+		UCB1Tuned{},
+		PUCT{C: 1, Prior: HeuristicPrior},
+		Thompson{},
+	}
+	for _, policy := range policies {
+		searcher := NewSearcher(Options{
+			Determinizations: 2,
+			Trajectories:     100,
+			Eval:             BinaryEval,
+			Policy:           policy,
+			Mode:             ISMCTS,
+			Seed:             5,
+		})
+		results, _ := searcher.Search(context.Background(), obs)
+		visits := 0
+		for _, result := range results {
+			if !containsMove(legal, result.Move) {
+				t.Fatalf("%T: illegal move %d in %v", policy, result.Move, results)
+			}
+			visits += result.Visits
+		}
+		if visits != 200 {
+			t.Fatalf("%T: %d visits, want 200", policy, visits)
+		}
+	}
+}
+
+func containsMove(moves []int, move int) bool {
+	for _, m := range moves {
+		if m == move {
+			return true
+		}
+	}
+	return false
+}
+
+// availableNode returns a node whose first child has the higher mean, and
+// whose second child was rarely available but visited almost every time it
+// was
+func availableNode() *Node {
+	node := NewNode()
+	node.N = 10000
+	often := &Node{Pos: 0, Parent: node, N: 90, X: 72, X2: 72, Avail: 100}
+	rare := &Node{Pos: 1, Parent: node, N: 10, X: 5, X2: 5, Avail: 10}
+	node.Children = []*Node{often, rare}
+	return node
+}
+
+func TestSelectAvailableCountsAvailability(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	policies := []AvailabilityPolicy{
+		UCB1{C: 1},
+		UCB1Tuned{},
+		PUCT{C: 1},
+	}
+	for _, policy := range policies {
+		node := availableNode()
+		// Against the visits of the parent the rarely available child is
+		// less explored
+		if child := policy.Select(node, nil, random); child.Pos != 1 {
+			t.Fatalf("%T: Select picked move %d, want 1", policy, child.Pos)
+		}
+		// Against its availability it has been explored enough
+		if child := policy.SelectAvailable(node, node.Children, nil, random); child.Pos != 0 {
+			t.Fatalf("%T: SelectAvailable picked move %d, want 0", policy, child.Pos)
+		}
+	}
+}
+
+func TestSelectAvailableSkipsUnavailable(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	policies := []AvailabilityPolicy{
+		UCB1{C: 1},
+		UCB1Tuned{},
+		PUCT{C: 1},
+		Thompson{},
+	}
+	for _, policy := range policies {
+		node := availableNode()
+		// An unvisited child is preferred by every policy but is not legal
+		node.Children = append(node.Children, &Node{Pos: 2, Parent: node})
+		for i := 0; i < 20; i++ {
+			if child := policy.SelectAvailable(node, node.Children[:2], nil, random); child.Pos == 2 {
+				t.Fatalf("%T: picked an unavailable child", policy)
+			}
+		}
+	}
+}
+
+type plainPolicy struct{}
+
+func (plainPolicy) Select(node *Node, tri *game.TriPeaks, random *rand.Rand) *Node {
+	return node.Children[0]
+}
+
+func TestISMCTSRejectsPlainPolicy(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("ISMCTS searched with a policy without SelectAvailable")
+		}
+	}()
+	SearchParallel(context.Background(), newGame(4).Observe(), Options{
+		Determinizations: 1,
+		Trajectories:     10,
+		Eval:             BinaryEval,
+		Policy:           plainPolicy{},
+		Mode:             ISMCTS,
+	})
+}
@@ -1,7 +1,7 @@
 package mcts
 
 import (
-	"math"
+	"context"
 	"math/rand"
 	"time"
 
@@ -10,137 +10,93 @@ import (
 )
 
 type SearchResult struct {
-	Move  int
-	Score float64
+	Move int
+	// Score is the sum of the rewards of the move over all trees
+	Score  float64
+	Visits int
+	Mean   float64
 }
 
 type SearchResults []SearchResult
 
-func (sr SearchResults) BestMove() int {
-	var (
-		max    float64
-		argMax int
-	)
-	for _, result := range sr {
-		if result.Score > max {
-			argMax = result.Move
-			max = result.Score
-		}
-	}
-	return argMax
+// BestMove returns the move with the highest score, ok is false if there are
+// no results
+func (sr SearchResults) BestMove() (move int, ok bool) {
+	return MaxScore{}.Choose(sr, nil)
 }
 
-func Search(tri *game.TriPeaks, determinizations, trajectories int, eval SimulationtEval) SearchResults {
-	initialLegalMoves, _ := tri.LegalMoves()
-	if len(initialLegalMoves) == 1 {
-		return SearchResults{SearchResult{Move: initialLegalMoves[0], Score: 1}}
-	}
-	random := rand.New(rand.NewSource(time.Now().UTC().UnixNano()))
-	unusedCards := hiddenCards(tri)
-	rootRewards := make(map[int]float64)
-	gameCopy := &(game.TriPeaks{})
-	var (
-		root            *Node
-		unusedCardsCopy []deck.Card
-	)
-	for i := 0; i < determinizations; i++ {
-		root = NewNode()
+func Search(obs *game.Observation, determinizations, trajectories int, eval SimulationtEval) SearchResults {
+	return SearchContext(context.Background(), obs, determinizations, trajectories, eval)
+}
 
-		for j := 0; j < trajectories; j++ {
-			unusedCardsCopy = make([]deck.Card, len(unusedCards))
-			gameCopy = tri.Copy()
-			deck.Copy(unusedCardsCopy, unusedCards)
-			data := &NodeData{
-				CardsLeft:          unusedCardsCopy,
-				CardsLeftBeginning: gameCopy.CardsLeft,
-			}
-			root.Data = data
-			node := Select(gameCopy, root)
-			if !gameCopy.GameOver() {
-				node = determinize(node, gameCopy, random)
-			}
-			reward := simulate(gameCopy, node, random, eval)
-			backpropagate(node, reward)
-		}
-		for _, child := range root.Children {
-			if _, exists := rootRewards[child.Pos]; exists {
-				rootRewards[child.Pos] += child.X
-			} else {
-				rootRewards[child.Pos] = child.X
-			}
-		}
-	}
-	searchResult := make([]SearchResult, 0)
-	for move, score := range rootRewards {
-		searchResult = append(searchResult, SearchResult{
-			Move:  move,
-			Score: score,
-		})
-	}
-	return searchResult
+// SearchFor searches determinizations for the given time and returns the
+// results found by then
+func SearchFor(obs *game.Observation, budget time.Duration, trajectories int, eval SimulationtEval) SearchResults {
+	ctx, cancel := context.WithTimeout(context.Background(), budget)
+	defer cancel()
+	results, _ := SearchParallel(ctx, obs, Options{
+		Trajectories: trajectories,
+		Eval:         eval,
+		Threads:      1,
+		Continuous:   true,
+	})
+	return results
 }
 
-func hiddenCards(game *game.TriPeaks) []deck.Card {
-	allCards := deck.New().Cards
-	usedCardsMap := make(map[int]struct{})
-	usedCards := game.UsedCards()
-	unusedCards := make([]deck.Card, 0, 10)
-	for _, card := range usedCards {
-		usedCardsMap[card.HashCode()] = struct{}{}
-	}
-	for _, card := range allCards {
-		if _, contains := usedCardsMap[card.HashCode()]; !contains {
-			unusedCards = append(unusedCards, card)
-		}
-	}
-	return unusedCards
+// SearchContext searches until ctx is done or the determinizations have been
+// searched. At least one trajectory is run if there are determinizations to
+// search so that the results contain a move.
+func SearchContext(ctx context.Context, obs *game.Observation, determinizations, trajectories int, eval SimulationtEval) SearchResults {
+	results, _ := SearchParallel(ctx, obs, Options{
+		Determinizations: determinizations,
+		Trajectories:     trajectories,
+		Eval:             eval,
+		Threads:          1,
+	})
+	return results
 }
 
-func Select(game *game.TriPeaks, node *Node) *Node {
+// Select descends the tree with the policy for as long as every move of the
+// node has been expanded
+func Select(game *game.TriPeaks, node *Node, data *NodeData, policy SelectionPolicy, random *rand.Rand) *Node {
 	selected := node
 	for game.CardsLeft > 0 {
 		moves, _ := game.LegalMoves()
 		totalMoves := len(moves)
-		if node.GetUnvisitedChild() == nil && len(node.Children) == totalMoves {
-			cNode := ucb1(selected)
-			cNode.Data = selected.Data
-			selected = cNode
-			applyNode(game, selected)
+		if totalMoves > 0 && selected.GetUnvisitedChild() == nil && len(selected.Children) == totalMoves {
+			selected = policy.Select(selected, game, random)
+			applyNode(game, selected, data)
 		} else {
 			break
 		}
 	}
 	return selected
 }
-func determinize(node *Node, game *game.TriPeaks, random *rand.Rand) *Node {
-	cNode := NewNode()
+
+// determinize plays the move chosen by the rollout policy out of all the
+// legal moves. A move that has not been expanded yet gets a child, dealing
+// the cards it reveals from the pool.
+func determinize(node *Node, game *game.TriPeaks, data *NodeData, rollout RolloutPolicy, random *rand.Rand) *Node {
 	moves, _ := game.LegalMoves()
-	usedMoves := make(map[int]struct{})
-	unusedMoves := make([]int, 0)
-	for _, child := range node.Children {
-		usedMoves[child.Pos] = struct{}{}
-	}
-	for _, move := range moves {
-		if _, contains := usedMoves[move]; !contains {
-			unusedMoves = append(unusedMoves, move)
-		}
-	}
-	if len(unusedMoves) == 0 {
-		ind := random.Intn(len(node.Children))
-		cNode = node.Children[ind]
-		cNode.Data = node.Data
-		applyNode(game, cNode)
+	move := rollout.Choose(game, moves, random)
+	if i := node.ChildPos(move); i != -1 {
+		cNode := node.Children[i]
+		applyNode(game, cNode, data)
 		return cNode
 	}
-	cNode.Pos = unusedMoves[random.Intn(len(unusedMoves))]
+	cNode := NewNode()
+	cNode.Pos = move
 	cNode.Parent = node
 	node.Children = append(node.Children, cNode)
-	cNode.Data = node.Data
 
 	if cNode.Pos == -1 {
-		ind := random.Intn(len(cNode.Data.CardsLeft))
-		randCard := cNode.Data.CardsLeft[ind]
-		cNode.Data.CardsLeft = deck.Remove(cNode.Data.CardsLeft, ind)
+		if game.StockKnown() {
+			applyNode(game, cNode, data)
+			return cNode
+		}
+		ind := random.Intn(len(data.CardsLeft))
+		randCard := data.CardsLeft[ind]
+		data.CardsLeft = deck.Remove(data.CardsLeft, ind)
 		cNode.LeftDet = Deter{
 			Card:        randCard,
 			Initialized: true,
@@ -158,9 +114,9 @@ func determinize(node *Node, game *game.TriPeaks, random *rand.Rand) *Node {
 			rightFound = cNode.GetParentDeterminization(rightPos, false)
 		}
 		if !leftFound && leftPos != -1 && game.Cards[leftPos].FaceDown && game.Cards[leftPos].ChildLeft-1 == 0 {
-			ind := random.Intn(len(cNode.Data.CardsLeft))
-			randCard := cNode.Data.CardsLeft[ind]
-			cNode.Data.CardsLeft = deck.Remove(cNode.Data.CardsLeft, ind)
+			ind := random.Intn(len(data.CardsLeft))
+			randCard := data.CardsLeft[ind]
+			data.CardsLeft = deck.Remove(data.CardsLeft, ind)
 			cNode.LeftDet = Deter{
 				Card:        randCard,
 				Pos:         leftPos,
@@ -168,9 +124,9 @@ func determinize(node *Node, game *game.TriPeaks, random *rand.Rand) *Node {
 			}
 		}
 		if !rightFound && rightPos != -1 && game.Cards[rightPos].FaceDown && game.Cards[rightPos].ChildLeft-1 == 0 {
-			ind := random.Intn(len(cNode.Data.CardsLeft))
-			randCard := cNode.Data.CardsLeft[ind]
-			cNode.Data.CardsLeft = deck.Remove(cNode.Data.CardsLeft, ind)
+			ind := random.Intn(len(data.CardsLeft))
+			randCard := data.CardsLeft[ind]
+			data.CardsLeft = deck.Remove(data.CardsLeft, ind)
 			cNode.RightDet = Deter{
 				Card:        randCard,
 				Pos:         rightPos,
@@ -178,58 +134,41 @@ func determinize(node *Node, game *game.TriPeaks, random *rand.Rand) *Node {
 			}
 		}
 	}
-	applyNode(game, cNode)
+	applyNode(game, cNode, data)
 	return cNode
 }
-func simulate(game *game.TriPeaks, node *Node, random *rand.Rand, eval SimulationtEval) float64 {
-	for !game.GameOver() {
-		node = determinize(node, game, random)
-	}
-	return eval(node, game)
-}
-
 func backpropagate(node *Node, reward float64) {
 	for ; node != nil; node = node.Parent {
-		node.X += reward
-		node.N++
+		node.update(1, reward, reward*reward)
 	}
 }
 
-func ucb1(node *Node) *Node {
-	highest := -1.0
-	var selected *Node
-	for _, cNode := range node.Children {
-		score := math.MaxFloat64
-		if cNode.N > 0 {
-			score = cNode.X + math.Sqrt(2*math.Log(float64(node.N))/float64(cNode.N))
-		}
-		if score > highest {
-			selected = cNode
-			highest = score
-		}
-	}
-	return selected
-}
-
-func applyNode(game *game.TriPeaks, node *Node) {
-	if node.Pos == -1 && node.LeftDet.Initialized {
-		deckLen := game.Stock.Len()
-		game.Stock.Cards[deckLen-1] = node.LeftDet.Card
-		game.Draw()
-		if node.LeftDet.Card.HashCode() != game.Discard().HashCode() {
+// applyNode plays the move of the node with its determinized cards. The cards
+// are removed from the pool in case the node was created by an earlier
+// trajectory.
+func applyNode(tri *game.TriPeaks, node *Node, data *NodeData) {
+	if node.Pos == -1 {
+		if !node.LeftDet.Initialized {
+			tri.Apply(game.Move{Kind: game.MoveDraw})
+			return
+		}
+		deckLen := tri.Stock.Len()
+		tri.Stock.Cards[deckLen-1] = node.LeftDet.Card
+		tri.Apply(game.Move{Kind: game.MoveDraw})
+		if node.LeftDet.Card.HashCode() != tri.Discard().HashCode() {
 			panic("Discard card differs from determinization, should not happen")
 		}
-		// unusedCards = RemoveCardVal(unusedCards, node.LeftDet.Card)
+		data.CardsLeft = deck.RemoveVal(data.CardsLeft, node.LeftDet.Card)
 	} else {
 		if leftDet := node.LeftDet; leftDet.Initialized {
-			game.Cards[leftDet.Pos].Card = leftDet.Card
-			// unusedCards = RemoveCardVal(unusedCards, leftDet.Card)
+			tri.Cards[leftDet.Pos].Card = leftDet.Card
+			data.CardsLeft = deck.RemoveVal(data.CardsLeft, leftDet.Card)
 		}
 		if rightDet := node.RightDet; rightDet.Initialized {
-			game.Cards[rightDet.Pos].Card = rightDet.Card
-			// unusedCards = RemoveCardVal(unusedCards, rightDet.Card)
+			tri.Cards[rightDet.Pos].Card = rightDet.Card
+			data.CardsLeft = deck.RemoveVal(data.CardsLeft, rightDet.Card)
 		}
-		legalMove := game.Select(node.Pos)
+		legalMove := tri.Apply(game.Move{Kind: game.MoveSelect, Pos: node.Pos})
 		if !legalMove {
 			panic("Game Tree contained illegal move, should not happen")
 		}
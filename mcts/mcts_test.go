@@ -0,0 +1,75 @@
+package mcts
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/MatiasLyyra/TriPeaks/deck"
+	"github.com/MatiasLyyra/TriPeaks/game"
+)
+
+func newGame(seed uint64) *game.TriPeaks {
+	stock := deck.New()
+	stock.ShuffleSeed(seed)
+	return game.NewTripeaks(*stock, game.DefaultRules())
+}
+
+// returnsWithin fails the test if search does not return in time
+func returnsWithin(t *testing.T, timeout time.Duration, search func() SearchResults) SearchResults {
+	t.Helper()
+	done := make(chan SearchResults, 1)
+	go func() {
+		done <- search()
+	}()
+	select {
+	case results := <-done:
+		return results
+	case <-time.After(timeout):
+		t.Fatalf("search did not return in %s", timeout)
+	}
+	return nil
+}
+
+func TestSearchWithoutDeterminizations(t *testing.T) {
+	obs := newGame(1).Observe()
+	results := returnsWithin(t, 5*time.Second, func() SearchResults {
+		return Search(obs, 0, 10, ScoreSigmoidEval)
+	})
+	if len(results) != 0 {
+		t.Fatalf("searching no determinizations gave results %v", results)
+	}
+}
+
+func TestSearchForStopsAtTheDeadline(t *testing.T) {
+	obs := newGame(1).Observe()
+	start := time.Now()
+	results := returnsWithin(t, 5*time.Second, func() SearchResults {
+		return SearchFor(obs, 50*time.Millisecond, 10, ScoreSigmoidEval)
+	})
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Fatalf("search returned after %s, before the deadline", elapsed)
+	}
+	if _, ok := results.BestMove(); !ok {
+		t.Fatalf("no move found")
+	}
+}
+
+func TestContinuousWithoutDeadline(t *testing.T) {
+	obs := newGame(1).Observe()
+	// Without a deadline the search stops after the determinizations
+	results := returnsWithin(t, 5*time.Second, func() SearchResults {
+		results, _ := SearchParallel(context.Background(), obs, Options{
+			Determinizations: 2,
+			Trajectories:     10,
+			Eval:             ScoreSigmoidEval,
+			Threads:          1,
+			Continuous:       true,
+			Seed:             1,
+		})
+		return results
+	})
+	if _, ok := results.BestMove(); !ok {
+		t.Fatalf("no move found")
+	}
+}
@@ -8,20 +8,63 @@ type Deter struct {
 	Initialized bool
 }
 
+// NodeData is the state of a single trajectory
 type NodeData struct {
+	// CardsLeft are the unseen cards that have not been assigned to a hidden
+	// card yet
 	CardsLeft          []deck.Card
 	CardsLeftBeginning int
 }
 
 type Node struct {
-	X        float64
-	N        int
+	// X is the sum of the rewards and X2 the sum of their squares
+	X  float64
+	X2 float64
+	N  int
+	// Avail counts the ISMCTS iterations in which the move of the node was
+	// legal when its parent was selected from
+	Avail    int
 	Pos      int
 	LeftDet  Deter
 	RightDet Deter
 	Parent   *Node
 	Children []*Node
-	Data     *NodeData
+	// Prior is the prior probability of the move given by PUCT
+	Prior     float64
+	hasPriors bool
+	// entry is shared with the transpositions of the node
+	entry *ttEntry
+}
+
+// Stats returns the visits, the sum of the rewards and the sum of their
+// squares. With a transposition table they are shared with the other nodes
+// of the same position.
+func (n *Node) Stats() (int, float64, float64) {
+	if n.entry != nil {
+		return n.entry.n, n.entry.x, n.entry.x2
+	}
+	return n.N, n.X, n.X2
+}
+
+// Mean returns the mean reward of Stats, 0 if there are no visits
+func (n *Node) Mean() float64 {
+	visits, x, _ := n.Stats()
+	if visits == 0 {
+		return 0
+	}
+	return x / float64(visits)
+}
+
+// update adds visits and rewards to the node and its entry
+func (n *Node) update(visits int, reward, rewardSq float64) {
+	n.N += visits
+	n.X += reward
+	n.X2 += rewardSq
+	if n.entry != nil {
+		n.entry.n += visits
+		n.entry.x += reward
+		n.entry.x2 += rewardSq
+	}
 }
 
 func (n *Node) GetUnvisitedChild() *Node {
@@ -65,10 +108,14 @@ func (n *Node) GetParentDeterminization(pos int, left bool) bool {
 		}
 	}
 	for parent := n.Parent; parent != nil; parent = parent.Parent {
-		if parent.LeftDet.Pos == pos {
+		// Draw nodes store the drawn card in LeftDet without a position
+		if parent.Pos == -1 {
+			continue
+		}
+		if parent.LeftDet.Initialized && parent.LeftDet.Pos == pos {
 			assignL(parent)
 			return true
-		} else if parent.RightDet.Pos == pos {
+		} else if parent.RightDet.Initialized && parent.RightDet.Pos == pos {
 			assignR(parent)
 			return true
 		}
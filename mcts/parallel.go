@@ -0,0 +1,290 @@
+package mcts
+
+import (
+	"context"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/MatiasLyyra/TriPeaks/deck"
+	"github.com/MatiasLyyra/TriPeaks/game"
+)
+
+// Options configure SearchParallel and Searcher
+type Options struct {
+	// Determinizations and Trajectories are searched by every thread
+	Determinizations int
+	Trajectories     int
+	// Continuous keeps searching new determinizations until the context is
+	// done instead of stopping after Determinizations. It is ignored if the
+	// context can never be done.
+	Continuous bool
+	Eval       SimulationtEval
+	// Policy selects the nodes to descend to, DefaultPolicy if nil
+	Policy SelectionPolicy
+	// Rollout chooses the moves of the play-outs, UniformRollout if nil
+	Rollout RolloutPolicy
+	Threads int
+	// TreeParallel makes the threads search the trees of the determinizations
+	// together instead of each thread searching trees of its own. Every
+	// tree is then searched with Threads * Trajectories trajectories.
+	TreeParallel bool
+	// VirtualLoss is subtracted from the reward of the nodes a thread is
+	// passing through in a tree parallel search, on top of counting the
+	// trajectory as a visit, so that the other threads try different paths
+	VirtualLoss float64
+	// Transpositions enables a transposition table holding at most this many
+	// positions, about 100 bytes each. Nodes of the same position in a tree
+	// then share their statistics. Every thread has a table of its own unless
+	// the threads share the trees. A Searcher keeps the tables between
+	// searches along with its trees. Not used by ISMCTS.
+	Transpositions int
+	// Mode selects the search algorithm, Determinized by default. ISMCTS
+	// needs a Policy that implements AvailabilityPolicy, as the built-in
+	// policies do.
+	Mode SearchMode
+	// Seed seeds the random sources of the threads, the current time is used
+	// if 0
+	Seed int64
+}
+
+// SearchParallel searches the observed game with Options.Threads threads,
+// each with its own random source, and sums the results of the threads. At
+// least one trajectory of every determinization is run even if ctx is done
+// so that the results contain a move.
+func SearchParallel(ctx context.Context, obs *game.Observation, options Options) (SearchResults, SearchStats) {
+	return NewSearcher(options).Search(ctx, obs)
+}
+
+// moveStats are the summed statistics of a move over several trees
+type moveStats struct {
+	reward float64
+	visits int
+	// The sums of the mean rewards of the trees and their squares
+	meanSum float64
+	meanSq  float64
+	trees   int
+}
+
+// addRoot adds the statistics of the children of the root
+func addRoot(stats map[int]moveStats, root *Node) {
+	for _, child := range root.Children {
+		s := stats[child.Pos]
+		s.reward += child.X
+		s.visits += child.N
+		if child.N > 0 {
+			mean := child.X / float64(child.N)
+			s.meanSum += mean
+			s.meanSq += mean * mean
+			s.trees++
+		}
+		stats[child.Pos] = s
+	}
+}
+
+func toResults(stats map[int]moveStats) SearchResults {
+	results := make(SearchResults, 0, len(stats))
+	for move, s := range stats {
+		result := SearchResult{
+			Move:   move,
+			Score:  s.reward,
+			Visits: s.visits,
+		}
+		if s.visits > 0 {
+			result.Mean = s.reward / float64(s.visits)
+		}
+		results = append(results, result)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Move < results[j].Move
+	})
+	return results
+}
+
+func isDone(ctx context.Context) bool {
+	select {
+	case <-ctx.Done():
+		return true
+	default:
+		return false
+	}
+}
+
+// tree is the tree of a single determinization. A shared tree is searched by
+// several workers at once and must be locked while it is being modified.
+type tree struct {
+	root        *Node
+	shared      bool
+	virtualLoss float64
+	mu          sync.Mutex
+	// tt is nil without a transposition table. The hashes are salted per
+	// tree so that only positions of the same tree share statistics.
+	tt   *transpositionTable
+	salt uint64
+}
+
+// attach gives a new node the entry of its position
+func (t *tree) attach(node *Node, tri *game.TriPeaks) {
+	if t.tt != nil && node.entry == nil {
+		node.entry = t.tt.lookup(positionKey(tri, t.salt))
+	}
+}
+
+func (t *tree) lock() {
+	if t.shared {
+		t.mu.Lock()
+	}
+}
+
+func (t *tree) unlock() {
+	if t.shared {
+		t.mu.Unlock()
+	}
+}
+
+// addVirtualLoss counts a pending trajectory through the node, and through its
+// parents if path is set
+func (t *tree) addVirtualLoss(node *Node, path bool) {
+	if !t.shared {
+		return
+	}
+	for ; node != nil; node = node.Parent {
+		node.update(1, -t.virtualLoss, 0)
+		if !path {
+			break
+		}
+	}
+}
+
+// backpropagate adds the reward to the node and its parents, replacing the
+// virtual loss of a shared tree
+func (t *tree) backpropagate(node *Node, reward float64) {
+	if !t.shared {
+		backpropagate(node, reward)
+		return
+	}
+	for ; node != nil; node = node.Parent {
+		node.update(0, reward+t.virtualLoss, reward*reward)
+	}
+}
+
+// worker holds what a single thread needs to run trajectories
+type worker struct {
+	obs     *game.Observation
+	mode    SearchMode
+	tri     *game.TriPeaks
+	unseen  []deck.Card
+	eval    SimulationtEval
+	policy  SelectionPolicy
+	rollout RolloutPolicy
+	random  *rand.Rand
+	// counters are the statistics of the trajectories run by the worker
+	counters counters
+	// tt is the transposition table of the trees of the worker, nil if
+	// disabled. It is kept by the Searcher between searches.
+	tt *transpositionTable
+	// The game copy and the card pool are reused between trajectories
+	game *game.TriPeaks
+	data *NodeData
+	// available holds the children ISMCTS can select from
+	available []*Node
+}
+
+func newWorker(obs *game.Observation, options Options, seed int64) *worker {
+	policy := options.Policy
+	if policy == nil {
+		policy = DefaultPolicy
+	}
+	rollout := options.Rollout
+	if rollout == nil {
+		rollout = UniformRollout{}
+	}
+	tri := obs.Game()
+	unseen := obs.UnseenCards()
+	return &worker{
+		obs:     obs,
+		mode:    options.Mode,
+		tri:     tri,
+		unseen:  unseen,
+		eval:    options.Eval,
+		policy:  policy,
+		rollout: rollout,
+		random:  rand.New(rand.NewSource(seed)),
+		game:    &game.TriPeaks{},
+		data: &NodeData{
+			CardsLeft:          make([]deck.Card, 0, len(unseen)),
+			CardsLeftBeginning: tri.CardsLeft,
+		},
+	}
+}
+
+// search continues the search of the trees and builds a new tree for each
+// missing determinization. The trees are topped up to the given number of
+// trajectories. Salts are the salts of the transposition table keys of the
+// trees. Returns the trees, their salts and the summed statistics of the
+// moves.
+func (w *worker) search(ctx context.Context, roots []*Node, salts []uint64, determinizations, trajectories int) ([]*Node, []uint64, map[int]moveStats) {
+	stats := make(map[int]moveStats)
+	cancelled := false
+	for i := 0; !cancelled && (i < determinizations || i < len(roots)); i++ {
+		if i == len(roots) {
+			roots = append(roots, NewNode())
+			salts = append(salts, w.random.Uint64())
+		}
+		t := &tree{root: roots[i], tt: w.tt, salt: salts[i]}
+		for j := t.root.N; j < trajectories && !cancelled; j++ {
+			w.trajectory(t)
+			cancelled = isDone(ctx)
+		}
+		addRoot(stats, t.root)
+		cancelled = cancelled || isDone(ctx)
+	}
+	return roots, salts, stats
+}
+
+// trajectory selects a path in the tree, plays it out to the end of the game
+// and backpropagates the reward. A shared tree is only locked for one step
+// at a time so that the workers can interleave.
+func (w *worker) trajectory(t *tree) {
+	if w.mode == ISMCTS {
+		w.ismctsTrajectory(t)
+		return
+	}
+	start := time.Now()
+	w.tri.CopyStateInto(w.game)
+	w.data.CardsLeft = append(w.data.CardsLeft[:0], w.unseen...)
+	t.lock()
+	node := Select(w.game, t.root, w.data, w.policy, w.random)
+	t.addVirtualLoss(node, true)
+	t.unlock()
+	selected := time.Now()
+	expanded := selected
+	rollout := 0
+	for !w.game.GameOver() {
+		t.lock()
+		children := len(node.Children)
+		parent := node
+		node = determinize(node, w.game, w.data, w.rollout, w.random)
+		if len(parent.Children) > children {
+			w.counters.nodes++
+		}
+		t.attach(node, w.game)
+		t.addVirtualLoss(node, false)
+		t.unlock()
+		if rollout == 0 {
+			expanded = time.Now()
+		}
+		rollout++
+	}
+	reward := w.eval(node, w.game)
+	simulated := time.Now()
+	t.lock()
+	t.backpropagate(node, reward)
+	t.unlock()
+	w.counters.selection += selected.Sub(start)
+	w.counters.expansion += expanded.Sub(selected)
+	w.counters.simulation += simulated.Sub(expanded)
+	w.counters.backprop += time.Since(simulated)
+	w.counters.addTrajectory(node, rollout)
+}
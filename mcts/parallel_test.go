@@ -0,0 +1,108 @@
+package mcts
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestSeededSearchIsReproducible(t *testing.T) {
+	obs := newGame(2).Observe()
+	for _, threads := range []int{1, 3} {
+		options := Options{
+			Determinizations: 3,
+			Trajectories:     50,
+			Eval:             ScoreSigmoidEval,
+			Threads:          threads,
+			Seed:             7,
+		}
+		first, _ := SearchParallel(context.Background(), obs, options)
+		second, _ := SearchParallel(context.Background(), obs, options)
+		if !reflect.DeepEqual(first, second) {
+			t.Fatalf("%d threads: seeded searches differ\n%v\n%v", threads, first, second)
+		}
+		options.Seed = 8
+		if other, _ := SearchParallel(context.Background(), obs, options); reflect.DeepEqual(first, other) {
+			t.Fatalf("%d threads: searches with different seeds are the same", threads)
+		}
+	}
+}
+
+// checkTree checks that the visits and the rewards of every node are those of
+// its children and that the mean reward is within the range of
+// ScoreSigmoidEval, which fails if a virtual loss was left behind
+func checkTree(t *testing.T, node *Node) {
+	t.Helper()
+	if node.X < 0 || node.X > float64(node.N) {
+		t.Fatalf("node of move %d has %d visits and reward %f", node.Pos, node.N, node.X)
+	}
+	if len(node.Children) == 0 {
+		return
+	}
+	visits, reward := 0, 0.0
+	for _, child := range node.Children {
+		visits += child.N
+		reward += child.X
+		checkTree(t, child)
+	}
+	if visits != node.N || math.Abs(reward-node.X) > 1e-6 {
+		t.Fatalf("node of move %d has %d visits and reward %f, its children %d and %f",
+			node.Pos, node.N, node.X, visits, reward)
+	}
+}
+
+func TestMergedResultsSumTheTrees(t *testing.T) {
+	obs := newGame(2).Observe()
+	tests := []struct {
+		name         string
+		threads      int
+		treeParallel bool
+		trees        int
+		visits       int
+	}{
+		{"single thread", 1, false, 3, 40},
+		{"root parallel", 4, false, 12, 40},
+		// The threads search the trees together
+		{"tree parallel", 4, true, 3, 160},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			searcher := NewSearcher(Options{
+				Determinizations: 3,
+				Trajectories:     40,
+				Eval:             ScoreSigmoidEval,
+				Threads:          test.threads,
+				TreeParallel:     test.treeParallel,
+				VirtualLoss:      1,
+				Seed:             3,
+			})
+			results, _ := searcher.Search(context.Background(), obs)
+			roots := searcher.Roots()
+			if len(roots) != test.trees {
+				t.Fatalf("%d trees, want %d", len(roots), test.trees)
+			}
+			visits := make(map[int]int)
+			reward := make(map[int]float64)
+			for _, root := range roots {
+				if root.N != test.visits {
+					t.Fatalf("root has %d visits, want %d", root.N, test.visits)
+				}
+				checkTree(t, root)
+				for _, child := range root.Children {
+					visits[child.Pos] += child.N
+					reward[child.Pos] += child.X
+				}
+			}
+			if len(results) != len(visits) {
+				t.Fatalf("results %v, moves of the trees %v", results, visits)
+			}
+			for _, result := range results {
+				if result.Visits != visits[result.Move] || math.Abs(result.Score-reward[result.Move]) > 1e-6 {
+					t.Fatalf("move %d has %d visits and score %f, the trees %d and %f",
+						result.Move, result.Visits, result.Score, visits[result.Move], reward[result.Move])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,248 @@
+package mcts
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/MatiasLyyra/TriPeaks/game"
+)
+
+// SelectionPolicy picks the child to descend to from a node whose moves have
+// all been expanded. The game is in the position of the node.
+type SelectionPolicy interface {
+	Select(node *Node, tri *game.TriPeaks, random *rand.Rand) *Node
+}
+
+// AvailabilityPolicy is a SelectionPolicy that ISMCTS can use. ISMCTS only
+// selects out of the children whose moves are legal in the sampled
+// determinization, and counts for each child the iterations in which it was
+// available, Node.Avail, in place of the visits of the parent.
+type AvailabilityPolicy interface {
+	SelectionPolicy
+	SelectAvailable(node *Node, available []*Node, tri *game.TriPeaks, random *rand.Rand) *Node
+}
+
+// DefaultPolicy is used when Options.Policy is nil
+var DefaultPolicy SelectionPolicy = UCB1{C: math.Sqrt2}
+
+// parentVisits returns the visits of the parent of the child
+func parentVisits(child *Node) int {
+	return child.Parent.N
+}
+
+// availability returns the number of iterations the child was available in
+func availability(child *Node) int {
+	return child.Avail
+}
+
+// UCB1 picks the child with the highest mean reward plus
+// C * sqrt(ln N / n). Unvisited children are picked first. With a
+// transposition table the mean is shared between transpositions while the
+// exploration term counts the visits of the node itself.
+type UCB1 struct {
+	C float64
+}
+
+func (p UCB1) Select(node *Node, tri *game.TriPeaks, random *rand.Rand) *Node {
+	return p.selectFrom(node.Children, parentVisits)
+}
+
+func (p UCB1) SelectAvailable(node *Node, available []*Node, tri *game.TriPeaks, random *rand.Rand) *Node {
+	return p.selectFrom(available, availability)
+}
+
+func (p UCB1) selectFrom(children []*Node, parentN func(*Node) int) *Node {
+	return argMax(children, func(child *Node) float64 {
+		if child.N == 0 {
+			return math.Inf(1)
+		}
+		logN := math.Log(float64(parentN(child)))
+		return child.Mean() + p.C*math.Sqrt(logN/float64(child.N))
+	})
+}
+
+// UCB1Tuned replaces the exploration term of UCB1 with one that uses the
+// variance of the rewards of the child, bounded by 1/4 which is the largest
+// variance of rewards between 0 and 1
+type UCB1Tuned struct{}
+
+func (p UCB1Tuned) Select(node *Node, tri *game.TriPeaks, random *rand.Rand) *Node {
+	return p.selectFrom(node.Children, parentVisits)
+}
+
+func (p UCB1Tuned) SelectAvailable(node *Node, available []*Node, tri *game.TriPeaks, random *rand.Rand) *Node {
+	return p.selectFrom(available, availability)
+}
+
+func (p UCB1Tuned) selectFrom(children []*Node, parentN func(*Node) int) *Node {
+	return argMax(children, func(child *Node) float64 {
+		if child.N == 0 {
+			return math.Inf(1)
+		}
+		logN := math.Log(float64(parentN(child)))
+		visits, x, x2 := child.Stats()
+		mean := x / float64(visits)
+		n := float64(child.N)
+		variance := x2/float64(visits) - mean*mean + math.Sqrt(2*logN/n)
+		return mean + math.Sqrt(logN/n*math.Min(0.25, variance))
+	})
+}
+
+// PriorFunc returns the prior probability of each of the moves in the
+// position, the probabilities should sum to 1
+type PriorFunc func(tri *game.TriPeaks, moves []int) []float64
+
+// PUCT picks the child with the highest mean reward plus
+// C * P * sqrt(N) / (1 + n) where P is the prior probability of the move.
+// The priors of the children are computed the first time a node is selected
+// from. Prior defaults to UniformPrior.
+type PUCT struct {
+	C     float64
+	Prior PriorFunc
+}
+
+func (p PUCT) Select(node *Node, tri *game.TriPeaks, random *rand.Rand) *Node {
+	p.setPriors(node, tri)
+	return p.selectFrom(node.Children, parentVisits)
+}
+
+// SelectAvailable uses the priors of all the children of the node, the
+// priors of the available ones are not normalized
+func (p PUCT) SelectAvailable(node *Node, available []*Node, tri *game.TriPeaks, random *rand.Rand) *Node {
+	p.setPriors(node, tri)
+	return p.selectFrom(available, availability)
+}
+
+func (p PUCT) selectFrom(children []*Node, parentN func(*Node) int) *Node {
+	return argMax(children, func(child *Node) float64 {
+		sqrtN := math.Sqrt(float64(parentN(child)))
+		return child.Mean() + p.C*child.Prior*sqrtN/float64(1+child.N)
+	})
+}
+
+// setPriors computes the priors of the children the first time the node is
+// selected from
+func (p PUCT) setPriors(node *Node, tri *game.TriPeaks) {
+	if !node.hasPriors {
+		prior := p.Prior
+		if prior == nil {
+			prior = UniformPrior
+		}
+		moves := make([]int, len(node.Children))
+		for i, child := range node.Children {
+			moves[i] = child.Pos
+		}
+		for i, probability := range prior(tri, moves) {
+			node.Children[i].Prior = probability
+		}
+		node.hasPriors = true
+	}
+}
+
+// UniformPrior gives every move the same probability
+func UniformPrior(tri *game.TriPeaks, moves []int) []float64 {
+	priors := make([]float64, len(moves))
+	for i := range priors {
+		priors[i] = 1 / float64(len(moves))
+	}
+	return priors
+}
+
+// HeuristicPrior prefers playing a card to drawing and playing a card that
+// turns other cards face up. A card is weighted e^(1 + revealed cards) and
+// drawing e^0.
+func HeuristicPrior(tri *game.TriPeaks, moves []int) []float64 {
+	priors := make([]float64, len(moves))
+	sum := 0.0
+	for i, move := range moves {
+		weight := 0.0
+		if move != -1 {
+			weight = 1 + float64(revealsCards(tri, move))
+		}
+		priors[i] = math.Exp(weight)
+		sum += priors[i]
+	}
+	for i := range priors {
+		priors[i] /= sum
+	}
+	return priors
+}
+
+// revealsCards returns how many face down cards removing the card at pos
+// turns face up
+func revealsCards(tri *game.TriPeaks, pos int) int {
+	revealed := 0
+	leftPos, rightPos := tri.CheckReveals(pos)
+	for _, covered := range []int{leftPos, rightPos} {
+		if covered != -1 && tri.Cards[covered].FaceDown && tri.Cards[covered].ChildLeft == 1 {
+			revealed++
+		}
+	}
+	return revealed
+}
+
+// Thompson samples the win probability of every child from a Beta posterior
+// and picks the child with the highest sample. The rewards are treated as
+// the fraction of a win, so it is meant for evals between 0 and 1 such as
+// BinaryEval.
+type Thompson struct{}
+
+func (p Thompson) Select(node *Node, tri *game.TriPeaks, random *rand.Rand) *Node {
+	return p.SelectAvailable(node, node.Children, tri, random)
+}
+
+// SelectAvailable samples the available children, the posterior does not
+// depend on the visits of the parent
+func (p Thompson) SelectAvailable(node *Node, available []*Node, tri *game.TriPeaks, random *rand.Rand) *Node {
+	return argMax(available, func(child *Node) float64 {
+		visits, x, _ := child.Stats()
+		n := float64(visits)
+		wins := math.Max(0, math.Min(n, x))
+		return betaSample(random, 1+wins, 1+n-wins)
+	})
+}
+
+// argMax returns the child with the highest score, the first one on ties
+func argMax(children []*Node, score func(*Node) float64) *Node {
+	var (
+		selected *Node
+		highest  float64
+	)
+	for _, child := range children {
+		s := score(child)
+		if selected == nil || s > highest {
+			selected = child
+			highest = s
+		}
+	}
+	return selected
+}
+
+// betaSample draws from Beta(a, b) using two Gamma samples
+func betaSample(random *rand.Rand, a, b float64) float64 {
+	x := gammaSample(random, a)
+	y := gammaSample(random, b)
+	return x / (x + y)
+}
+
+// gammaSample draws from Gamma(shape, 1) with the method of Marsaglia and
+// Tsang. Shapes below 1 are boosted by one and scaled back.
+func gammaSample(random *rand.Rand, shape float64) float64 {
+	if shape < 1 {
+		return gammaSample(random, shape+1) * math.Pow(random.Float64(), 1/shape)
+	}
+	d := shape - 1.0/3
+	c := 1 / math.Sqrt(9*d)
+	for {
+		x := random.NormFloat64()
+		v := 1 + c*x
+		if v <= 0 {
+			continue
+		}
+		v = v * v * v
+		u := random.Float64()
+		if math.Log(u) < 0.5*x*x+d-d*v+d*math.Log(v) {
+			return d * v
+		}
+	}
+}
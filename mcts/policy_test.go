@@ -0,0 +1,164 @@
+package mcts
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/MatiasLyyra/TriPeaks/game"
+)
+
+// newParent returns a node with N visits and the given children
+func newParent(n int, children ...*Node) *Node {
+	node := NewNode()
+	node.N = n
+	for i, child := range children {
+		child.Pos = i
+		child.Parent = node
+	}
+	node.Children = children
+	return node
+}
+
+func TestUCB1TunedPicksUnvisited(t *testing.T) {
+	node := newParent(100,
+		&Node{N: 60, X: 54, X2: 54},
+		&Node{N: 40, X: 20, X2: 20},
+		&Node{},
+	)
+	if child := (UCB1Tuned{}).Select(node, nil, nil); child.Pos != 2 {
+		t.Fatalf("picked move %d, want the unvisited 2", child.Pos)
+	}
+}
+
+func TestUCB1TunedExploresVariance(t *testing.T) {
+	// The same mean, the second child's rewards are either 0 or 1 and the
+	// first child's always 0.5
+	node := newParent(10000,
+		&Node{N: 5000, X: 2500, X2: 1250},
+		&Node{N: 5000, X: 2500, X2: 2500},
+	)
+	if child := (UCB1Tuned{}).Select(node, nil, nil); child.Pos != 1 {
+		t.Fatalf("picked move %d, want the varying 1", child.Pos)
+	}
+	// A much higher mean outweighs the variance
+	node.Children[0].X, node.Children[0].X2 = 4000, 3200
+	if child := (UCB1Tuned{}).Select(node, nil, nil); child.Pos != 0 {
+		t.Fatalf("picked move %d, want the better 0", child.Pos)
+	}
+}
+
+func TestPUCT(t *testing.T) {
+	tests := []struct {
+		name   string
+		priors []float64
+		nodes  []*Node
+		want   int
+	}{
+		{
+			"unvisited with a prior",
+			[]float64{0.5, 0.5},
+			[]*Node{{N: 100, X: 90}, {}},
+			1,
+		},
+		{
+			"zero prior is never explored",
+			[]float64{1, 0},
+			[]*Node{{N: 100, X: 10}, {}},
+			0,
+		},
+		{
+			"zero prior keeps its mean",
+			[]float64{0, 1},
+			[]*Node{{N: 10, X: 9}, {N: 90, X: 9}},
+			0,
+		},
+		{
+			"prior breaks even means",
+			[]float64{0.2, 0.8},
+			[]*Node{{N: 50, X: 25}, {N: 50, X: 25}},
+			1,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			calls := 0
+			policy := PUCT{C: 1, Prior: func(tri *game.TriPeaks, moves []int) []float64 {
+				calls++
+				return test.priors
+			}}
+			node := newParent(100, test.nodes...)
+			for i := 0; i < 2; i++ {
+				if child := policy.Select(node, nil, nil); child.Pos != test.want {
+					t.Fatalf("picked move %d, want %d", child.Pos, test.want)
+				}
+			}
+			if calls != 1 {
+				t.Fatalf("priors computed %d times, want once", calls)
+			}
+			for i, child := range node.Children {
+				if child.Prior != test.priors[i] {
+					t.Fatalf("move %d has prior %f, want %f", i, child.Prior, test.priors[i])
+				}
+			}
+		})
+	}
+}
+
+func TestPUCTUniformPrior(t *testing.T) {
+	node := newParent(0, &Node{}, &Node{}, &Node{}, &Node{})
+	(PUCT{C: 1}).Select(node, nil, nil)
+	for _, child := range node.Children {
+		if child.Prior != 0.25 {
+			t.Fatalf("move %d has prior %f, want 0.25", child.Pos, child.Prior)
+		}
+	}
+}
+
+func TestThompson(t *testing.T) {
+	tests := []struct {
+		name  string
+		nodes []*Node
+		want  int
+		// min is the fewest of the 100 selections that pick want
+		min int
+	}{
+		{"winner", []*Node{{N: 100, X: 10}, {N: 100, X: 90}}, 1, 100},
+		{"unvisited over a loser", []*Node{{N: 100, X: 0}, {}}, 1, 95},
+		{"winner over unvisited", []*Node{{}, {N: 100, X: 100}}, 1, 95},
+		// Rewards outside of [0, 1] are clamped
+		{"clamped", []*Node{{N: 10, X: -5}, {N: 10, X: 20}}, 1, 100},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			random := rand.New(rand.NewSource(1))
+			node := newParent(200, test.nodes...)
+			picked := 0
+			for i := 0; i < 100; i++ {
+				if (Thompson{}).Select(node, nil, random).Pos == test.want {
+					picked++
+				}
+			}
+			if picked < test.min {
+				t.Fatalf("picked move %d %d times out of 100, want at least %d", test.want, picked, test.min)
+			}
+		})
+	}
+}
+
+func TestThompsonIsSeeded(t *testing.T) {
+	node := newParent(20, &Node{N: 10, X: 5}, &Node{N: 10, X: 5}, &Node{})
+	picks := func() []int {
+		random := rand.New(rand.NewSource(3))
+		moves := make([]int, 50)
+		for i := range moves {
+			moves[i] = (Thompson{}).Select(node, nil, random).Pos
+		}
+		return moves
+	}
+	first, second := picks(), picks()
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("seeded selections differ\n%v\n%v", first, second)
+		}
+	}
+}
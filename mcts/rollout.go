@@ -0,0 +1,317 @@
+package mcts
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/MatiasLyyra/TriPeaks/deck"
+	"github.com/MatiasLyyra/TriPeaks/game"
+)
+
+// RolloutPolicy chooses the moves that expand the tree and play the game out
+// after the selection. Moves are the legal moves, in an ISMCTS expansion the
+// ones that have not been expanded yet. The hidden cards of the game are
+// blank, so a policy only sees what the player would.
+type RolloutPolicy interface {
+	Choose(tri *game.TriPeaks, moves []int, random *rand.Rand) int
+}
+
+// UniformRollout chooses a random move. It is used when Options.Rollout is
+// nil.
+type UniformRollout struct{}
+
+func (p UniformRollout) Choose(tri *game.TriPeaks, moves []int, random *rand.Rand) int {
+	return moves[random.Intn(len(moves))]
+}
+
+// NoDrawRollout chooses a random card to play and only draws when no card
+// can be played
+type NoDrawRollout struct{}
+
+func (p NoDrawRollout) Choose(tri *game.TriPeaks, moves []int, random *rand.Rand) int {
+	cards := 0
+	for _, move := range moves {
+		if move != -1 {
+			cards++
+		}
+	}
+	if cards == 0 {
+		return moves[0]
+	}
+	ind := random.Intn(cards)
+	for _, move := range moves {
+		if move != -1 {
+			if ind == 0 {
+				return move
+			}
+			ind--
+		}
+	}
+	return moves[0]
+}
+
+// LookaheadRollout plays the card that starts the longest run of cards that
+// can be played without drawing, looking Depth cards ahead. Cards turned
+// face up by the run are not known and end it. Draws only when no card can
+// be played.
+type LookaheadRollout struct {
+	Depth int
+}
+
+func (p LookaheadRollout) Choose(tri *game.TriPeaks, moves []int, random *rand.Rand) int {
+	depth := p.Depth
+	if depth <= 0 {
+		depth = 6
+	}
+	r := newRunSearch(tri)
+	best := -1
+	bestMove := moves[0]
+	ties := 0
+	for _, move := range moves {
+		if move == -1 {
+			continue
+		}
+		length := r.play(move, depth)
+		if length > best {
+			best = length
+			bestMove = move
+			ties = 1
+		} else if length == best {
+			// Picks uniformly between the moves with the longest run
+			ties++
+			if random.Intn(ties) == 0 {
+				bestMove = move
+			}
+		}
+	}
+	return bestMove
+}
+
+// runSearch finds the longest run of cards on a scratch copy of the tableau
+type runSearch struct {
+	tri       *game.TriPeaks
+	childLeft []int
+	removed   []bool
+}
+
+func newRunSearch(tri *game.TriPeaks) *runSearch {
+	r := &runSearch{
+		tri:       tri,
+		childLeft: make([]int, len(tri.Cards)),
+		removed:   make([]bool, len(tri.Cards)),
+	}
+	for pos, card := range tri.Cards {
+		r.childLeft[pos] = card.ChildLeft
+		r.removed[pos] = card.Removed
+	}
+	return r
+}
+
+// play returns the length of the longest run that starts with the card at
+// pos
+func (r *runSearch) play(pos, depth int) int {
+	covers := r.tri.Layout.Slots[pos].Covers
+	r.removed[pos] = true
+	for _, covered := range covers {
+		r.childLeft[covered]--
+	}
+	longest := 0
+	if depth > 1 {
+		longest = r.longest(r.tri.Cards[pos].Card, depth-1)
+	}
+	for _, covered := range covers {
+		r.childLeft[covered]++
+	}
+	r.removed[pos] = false
+	return 1 + longest
+}
+
+func (r *runSearch) longest(discard deck.Card, depth int) int {
+	longest := 0
+	for pos, card := range r.tri.Cards {
+		if r.removed[pos] || r.childLeft[pos] > 0 || card.FaceDown || !r.tri.Rules.Playable(card.Card, discard) {
+			continue
+		}
+		if length := r.play(pos, depth); length > longest {
+			longest = length
+		}
+	}
+	return longest
+}
+
+// The features describing a move for EpsilonGreedy and Learned:
+//  0. 1 for playing a card, 0 for drawing
+//  1. the number of face down cards the move turns face up
+//  2. the number of other cards that can be played on the card next
+//  3. the height of the card on the tableau, 1 for the top row
+//  4. the fraction of the deck left in the stock when drawing
+const rolloutFeatures = 5
+
+// HeuristicWeights always prefer playing a card to drawing, then cards that
+// turn cards face up and leave more cards to play next
+var HeuristicWeights = []float64{2, 1, 0.5, 0.25, 0}
+
+func moveFeatures(tri *game.TriPeaks, move int, features []float64) {
+	for i := range features {
+		features[i] = 0
+	}
+	if move == -1 {
+		features[4] = float64(tri.Stock.Len()) / 52
+		return
+	}
+	card := tri.Cards[move]
+	features[0] = 1
+	features[1] = float64(revealsCards(tri, move))
+	for pos, other := range tri.Cards {
+		if pos != move && !other.Removed && !other.FaceDown && other.ChildLeft == 0 && tri.Rules.Playable(other.Card, card.Card) {
+			features[2]++
+		}
+	}
+	lastRow := 0
+	for _, slot := range tri.Layout.Slots {
+		if slot.Row > lastRow {
+			lastRow = slot.Row
+		}
+	}
+	if lastRow > 0 {
+		features[3] = 1 - float64(tri.Layout.Slots[move].Row)/float64(lastRow)
+	}
+}
+
+// movesFeatures returns the features of every move
+func movesFeatures(tri *game.TriPeaks, moves []int) [][]float64 {
+	features := make([][]float64, len(moves))
+	for i, move := range moves {
+		features[i] = make([]float64, rolloutFeatures)
+		moveFeatures(tri, move, features[i])
+	}
+	return features
+}
+
+func dot(weights, features []float64) float64 {
+	sum := 0.0
+	for i, w := range weights {
+		sum += w * features[i]
+	}
+	return sum
+}
+
+// EpsilonGreedy chooses a random move with probability Epsilon and otherwise
+// the move with the highest heuristic score, the weighted sum of the move
+// features. Weights default to HeuristicWeights.
+type EpsilonGreedy struct {
+	Epsilon float64
+	Weights []float64
+}
+
+func (p EpsilonGreedy) Choose(tri *game.TriPeaks, moves []int, random *rand.Rand) int {
+	if random.Float64() < p.Epsilon {
+		return moves[random.Intn(len(moves))]
+	}
+	weights := p.Weights
+	if weights == nil {
+		weights = HeuristicWeights
+	}
+	features := make([]float64, rolloutFeatures)
+	bestMove := moves[0]
+	best := math.Inf(-1)
+	ties := 0
+	for _, move := range moves {
+		moveFeatures(tri, move, features)
+		score := dot(weights, features)
+		if score > best {
+			best = score
+			bestMove = move
+			ties = 1
+		} else if score == best {
+			ties++
+			if random.Intn(ties) == 0 {
+				bestMove = move
+			}
+		}
+	}
+	return bestMove
+}
+
+// Learned samples moves from a softmax over the weighted move features. The
+// weights are trained with TrainLearned.
+type Learned struct {
+	Weights []float64
+}
+
+func (p Learned) Choose(tri *game.TriPeaks, moves []int, random *rand.Rand) int {
+	probabilities := p.probabilities(movesFeatures(tri, moves))
+	return moves[sample(probabilities, random)]
+}
+
+func (p Learned) probabilities(features [][]float64) []float64 {
+	probabilities := make([]float64, len(features))
+	highest := math.Inf(-1)
+	for i, f := range features {
+		probabilities[i] = dot(p.Weights, f)
+		highest = math.Max(highest, probabilities[i])
+	}
+	sum := 0.0
+	for i := range probabilities {
+		probabilities[i] = math.Exp(probabilities[i] - highest)
+		sum += probabilities[i]
+	}
+	for i := range probabilities {
+		probabilities[i] /= sum
+	}
+	return probabilities
+}
+
+func sample(probabilities []float64, random *rand.Rand) int {
+	x := random.Float64()
+	for i, probability := range probabilities {
+		x -= probability
+		if x < 0 {
+			return i
+		}
+	}
+	return len(probabilities) - 1
+}
+
+// TrainLearned learns the weights of a Learned policy by letting it play
+// games against itself. The weights start from zero, a uniform policy, and
+// are updated with REINFORCE after every game, the reward being the
+// fraction of the tableau cleared.
+func TrainLearned(games int, rules game.Rules, seed int64) Learned {
+	const rate = 0.02
+	random := rand.New(rand.NewSource(seed))
+	policy := Learned{
+		Weights: make([]float64, rolloutFeatures),
+	}
+	gradient := make([]float64, rolloutFeatures)
+	baseline := 0.0
+	for i := 0; i < games; i++ {
+		stock := deck.New()
+		stock.ShuffleRand(random)
+		tri := game.NewTripeaks(*stock, rules)
+		for k := range gradient {
+			gradient[k] = 0
+		}
+		for !tri.GameOver() {
+			moves, _ := tri.LegalMoves()
+			features := movesFeatures(tri, moves)
+			probabilities := policy.probabilities(features)
+			choice := sample(probabilities, random)
+			// The gradient of log softmax is the chosen features minus
+			// their expectation
+			for k := range gradient {
+				gradient[k] += features[choice][k]
+				for j, f := range features {
+					gradient[k] -= probabilities[j] * f[k]
+				}
+			}
+			tri.Apply(game.MoveFromPos(moves[choice]))
+		}
+		reward := 1 - float64(tri.CardsLeft)/float64(len(tri.Cards))
+		for k := range policy.Weights {
+			policy.Weights[k] += rate * (reward - baseline) * gradient[k]
+		}
+		baseline += 0.01 * (reward - baseline)
+	}
+	return policy
+}
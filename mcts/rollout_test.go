@@ -0,0 +1,60 @@
+package mcts
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/MatiasLyyra/TriPeaks/game"
+)
+
+func TestRolloutsChooseLegalMoves(t *testing.T) {
+	policies := []RolloutPolicy{
+		UniformRollout{},
+		NoDrawRollout{},
+		LookaheadRollout{Depth: 3},
+		EpsilonGreedy{Epsilon: 0.2},
+		TrainLearned(10, game.DefaultRules(), 1),
+	}
+	for _, policy := range policies {
+		random := rand.New(rand.NewSource(1))
+		for seed := uint64(1); seed <= 5; seed++ {
+			tri := newGame(seed)
+			for !tri.GameOver() {
+				moves, _ := tri.LegalMoves()
+				move := policy.Choose(tri, moves, random)
+				if !containsMove(moves, move) {
+					t.Fatalf("%T chose %d out of %v", policy, move, moves)
+				}
+				tri.Play(game.MoveFromPos(move))
+			}
+		}
+	}
+}
+
+func TestNoDrawRolloutPlaysCards(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	for i := 0; i < 20; i++ {
+		if move := (NoDrawRollout{}).Choose(nil, []int{-1, 4, 7}, random); move == -1 {
+			t.Fatalf("drew with cards to play")
+		}
+	}
+	if move := (NoDrawRollout{}).Choose(nil, []int{-1}, random); move != -1 {
+		t.Fatalf("chose %d, the only move is to draw", move)
+	}
+}
+
+func TestTrainLearnedIsSeeded(t *testing.T) {
+	rules := game.DefaultRules()
+	first := TrainLearned(50, rules, 3)
+	second := TrainLearned(50, rules, 3)
+	if !reflect.DeepEqual(first, second) {
+		t.Fatalf("seeded training differs\n%v\n%v", first.Weights, second.Weights)
+	}
+	if other := TrainLearned(50, rules, 4); reflect.DeepEqual(first, other) {
+		t.Fatalf("training with different seeds gave the same weights %v", first.Weights)
+	}
+	if len(first.Weights) != rolloutFeatures {
+		t.Fatalf("%d weights, want %d", len(first.Weights), rolloutFeatures)
+	}
+}
@@ -0,0 +1,297 @@
+package mcts
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/MatiasLyyra/TriPeaks/deck"
+	"github.com/MatiasLyyra/TriPeaks/game"
+)
+
+// Searcher keeps its trees between searches. After a move has been played,
+// Advance keeps the subtrees that agree with the move and the cards it
+// revealed, so that the next search continues from the trajectories that
+// were already run instead of starting over.
+type Searcher struct {
+	Options Options
+	// trees holds the roots of the trees of every worker, in a tree parallel
+	// search the workers share the first list
+	trees [][]*Node
+	// salts are the salts of the transposition table keys of the trees and
+	// tables the transposition tables of the workers. They are kept with the
+	// trees so that the nodes kept by Advance share their entries with the
+	// nodes of the next search.
+	salts  [][]uint64
+	tables []*transpositionTable
+}
+
+func NewSearcher(options Options) *Searcher {
+	return &Searcher{
+		Options: options,
+	}
+}
+
+// Search searches the observed game like SearchParallel, continuing from the
+// trees kept by the earlier searches. The trees of the determinizations are
+// topped up to Options.Trajectories trajectories. A single legal move is
+// returned without searching, the trees are then dropped.
+func (s *Searcher) Search(ctx context.Context, obs *game.Observation) (SearchResults, SearchStats) {
+	start := time.Now()
+	initialLegalMoves, _ := obs.LegalMoves()
+	if len(initialLegalMoves) == 1 {
+		s.Reset()
+		results := SearchResults{SearchResult{Move: initialLegalMoves[0], Score: 1, Visits: 1, Mean: 1}}
+		return results, counters{}.stats(nil, time.Since(start))
+	}
+	options := s.Options
+	if options.Mode == ISMCTS {
+		if _, ok := options.Policy.(AvailabilityPolicy); options.Policy != nil && !ok {
+			panic("ISMCTS needs a Policy that implements AvailabilityPolicy")
+		}
+		for _, roots := range s.trees {
+			for _, root := range roots {
+				pruneIllegal(root, initialLegalMoves)
+			}
+		}
+	}
+	threads := options.Threads
+	if threads < 1 {
+		threads = 1
+	}
+	seed := options.Seed
+	if seed == 0 {
+		seed = time.Now().UTC().UnixNano()
+	}
+	seeds := rand.New(rand.NewSource(seed))
+	workers := make([]*worker, threads)
+	for i := range workers {
+		workers[i] = newWorker(obs, options, seeds.Int63())
+	}
+	s.attachTables(workers)
+	determinizations := options.Determinizations
+	trajectories := options.Trajectories
+	continuous := options.Continuous && ctx.Done() != nil
+	if options.Mode == ISMCTS {
+		// A single tree searched with the trajectories of every
+		// determinization
+		trajectories *= determinizations
+		if continuous {
+			trajectories = math.MaxInt32
+		}
+		determinizations = 1
+	} else if continuous {
+		determinizations = math.MaxInt32
+	}
+	if options.TreeParallel && threads > 1 {
+		if len(s.trees) != 1 {
+			s.trees = make([][]*Node, 1)
+			s.salts = make([][]uint64, 1)
+		}
+		var stats map[int]moveStats
+		s.trees[0], s.salts[0], stats = searchShared(ctx, workers, s.trees[0], s.salts[0], determinizations, trajectories, options.VirtualLoss)
+		return toResults(stats), sumCounters(workers).stats(stats, time.Since(start))
+	}
+
+	if len(s.trees) != threads {
+		s.trees = make([][]*Node, threads)
+		s.salts = make([][]uint64, threads)
+	}
+	stats := make([]map[int]moveStats, threads)
+	var wg sync.WaitGroup
+	for i, w := range workers {
+		wg.Add(1)
+		go func(i int, w *worker) {
+			defer wg.Done()
+			s.trees[i], s.salts[i], stats[i] = w.search(ctx, s.trees[i], s.salts[i], determinizations, trajectories)
+		}(i, w)
+	}
+	wg.Wait()
+	// Summed in thread order so that a seeded search gives the same scores
+	total := make(map[int]moveStats)
+	for _, threadStats := range stats {
+		for move, s := range threadStats {
+			t := total[move]
+			t.reward += s.reward
+			t.visits += s.visits
+			t.meanSum += s.meanSum
+			t.meanSq += s.meanSq
+			t.trees += s.trees
+			total[move] = t
+		}
+	}
+	return toResults(total), sumCounters(workers).stats(total, time.Since(start))
+}
+
+// attachTables gives the workers the transposition tables of the earlier
+// searches, creating them on the first search. The threads of a tree
+// parallel search share the table of the first worker.
+func (s *Searcher) attachTables(workers []*worker) {
+	options := s.Options
+	if options.Transpositions <= 0 || options.Mode != Determinized {
+		s.tables = nil
+		return
+	}
+	tables := len(workers)
+	if options.TreeParallel {
+		tables = 1
+	}
+	if len(s.tables) != tables || s.tables[0].capacity != options.Transpositions {
+		s.tables = make([]*transpositionTable, tables)
+		for i := range s.tables {
+			s.tables[i] = newTranspositionTable(options.Transpositions)
+		}
+	}
+	for i, w := range workers {
+		w.tt = s.tables[i%tables]
+	}
+}
+
+func sumCounters(workers []*worker) counters {
+	var total counters
+	for _, w := range workers {
+		total.add(w.counters)
+	}
+	return total
+}
+
+// Advance re-roots the trees on the move that was played. Revealed are the
+// cards turned face up by the move in the order of their slots, or the card
+// drawn from the stock. Trees that did not explore the move or determinized
+// different cards are dropped.
+func (s *Searcher) Advance(move game.Move, revealed []deck.Card) {
+	if move.Kind == game.MoveSurrender {
+		s.Reset()
+		return
+	}
+	pos := move.Pos
+	if move.Kind == game.MoveDraw {
+		pos = -1
+	}
+	for i, roots := range s.trees {
+		kept := roots[:0]
+		keptSalts := s.salts[i][:0]
+		for j, root := range roots {
+			for _, child := range root.Children {
+				// The information set tree of ISMCTS does not depend on
+				// the cards revealed
+				if child.Pos == pos && (s.Options.Mode == ISMCTS || matchesReveals(child, revealed)) {
+					child.Parent = nil
+					kept = append(kept, child)
+					keptSalts = append(keptSalts, s.salts[i][j])
+					break
+				}
+			}
+		}
+		for j := len(kept); j < len(roots); j++ {
+			roots[j] = nil
+		}
+		s.trees[i] = kept
+		s.salts[i] = keptSalts
+	}
+}
+
+// pruneIllegal drops the children of the root whose moves are not legal. An
+// information set tree kept by Advance was expanded under sampled cards, its
+// root can hold moves on cards that turned out to be covered or different.
+func pruneIllegal(root *Node, legalMoves []int) {
+	kept := root.Children[:0]
+	for _, child := range root.Children {
+		for _, move := range legalMoves {
+			if child.Pos == move {
+				kept = append(kept, child)
+				break
+			}
+		}
+	}
+	for i := len(kept); i < len(root.Children); i++ {
+		root.Children[i] = nil
+	}
+	root.Children = kept
+}
+
+// Roots returns the roots of the trees kept from the last search
+func (s *Searcher) Roots() []*Node {
+	var roots []*Node
+	for _, threadRoots := range s.trees {
+		roots = append(roots, threadRoots...)
+	}
+	return roots
+}
+
+// Reset drops the trees and the transposition tables, the next search
+// starts from scratch
+func (s *Searcher) Reset() {
+	s.trees = nil
+	s.salts = nil
+	s.tables = nil
+}
+
+func matchesReveals(node *Node, revealed []deck.Card) bool {
+	if node.Pos == -1 {
+		if !node.LeftDet.Initialized {
+			return true
+		}
+		return len(revealed) == 1 && revealed[0].HashCode() == node.LeftDet.Card.HashCode()
+	}
+	dets := make([]Deter, 0, 2)
+	for _, det := range []Deter{node.LeftDet, node.RightDet} {
+		if det.Initialized {
+			dets = append(dets, det)
+		}
+	}
+	sort.Slice(dets, func(i, j int) bool {
+		return dets[i].Pos < dets[j].Pos
+	})
+	if len(dets) != len(revealed) {
+		return false
+	}
+	for i, det := range dets {
+		if det.Card.HashCode() != revealed[i].HashCode() {
+			return false
+		}
+	}
+	return true
+}
+
+// searchShared runs all workers on the same tree for each determinization
+func searchShared(ctx context.Context, workers []*worker, roots []*Node, salts []uint64, determinizations, trajectories int, virtualLoss float64) ([]*Node, []uint64, map[int]moveStats) {
+	stats := make(map[int]moveStats)
+	for i := 0; i < determinizations || i < len(roots); i++ {
+		if i == len(roots) {
+			roots = append(roots, NewNode())
+			salts = append(salts, workers[0].random.Uint64())
+		}
+		t := &tree{
+			root:        roots[i],
+			shared:      true,
+			virtualLoss: virtualLoss,
+			tt:          workers[0].tt,
+			salt:        salts[i],
+		}
+		remaining := int64(len(workers))*int64(trajectories) - int64(t.root.N)
+		var wg sync.WaitGroup
+		for _, w := range workers {
+			wg.Add(1)
+			go func(w *worker) {
+				defer wg.Done()
+				for atomic.AddInt64(&remaining, -1) >= 0 {
+					w.trajectory(t)
+					if isDone(ctx) {
+						return
+					}
+				}
+			}(w)
+		}
+		wg.Wait()
+		addRoot(stats, t.root)
+		if isDone(ctx) {
+			break
+		}
+	}
+	return roots, salts, stats
+}
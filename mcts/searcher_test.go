@@ -0,0 +1,142 @@
+package mcts
+
+import (
+	"context"
+	"testing"
+
+	"github.com/MatiasLyyra/TriPeaks/deck"
+	"github.com/MatiasLyyra/TriPeaks/game"
+)
+
+func reuseOptions() Options {
+	return Options{
+		Determinizations: 4,
+		Trajectories:     60,
+		Eval:             ScoreSigmoidEval,
+		Threads:          1,
+		Seed:             9,
+	}
+}
+
+// drawChild returns the child of the root for drawing a card that has been
+// dealt its card
+func drawChild(t *testing.T, root *Node) *Node {
+	t.Helper()
+	for _, child := range root.Children {
+		if child.Pos == -1 && child.LeftDet.Initialized {
+			return child
+		}
+	}
+	t.Fatalf("no draw was searched")
+	return nil
+}
+
+func TestAdvanceKeepsMatchingSubtree(t *testing.T) {
+	tri := newGame(4)
+	searcher := NewSearcher(reuseOptions())
+	searcher.Search(context.Background(), tri.Observe())
+	child := drawChild(t, searcher.Roots()[0])
+	searcher.Advance(game.Move{Kind: game.MoveDraw}, []deck.Card{child.LeftDet.Card})
+	roots := searcher.Roots()
+	if len(roots) == 0 || roots[0] != child {
+		t.Fatalf("the subtree of the draw was not kept")
+	}
+	for _, root := range roots {
+		if root.Parent != nil {
+			t.Fatalf("kept root has a parent")
+		}
+		if root.Pos != -1 || root.LeftDet.Card.HashCode() != child.LeftDet.Card.HashCode() {
+			t.Fatalf("kept subtree of move %d drawing %s", root.Pos, root.LeftDet.Card)
+		}
+	}
+	visits := child.N
+
+	// Deal the card of the subtree to the top of the stock, draw it and
+	// continue the search
+	top := &tri.Stock.Cards[tri.Stock.Len()-1]
+	for i := range tri.Cards {
+		if tri.Cards[i].HashCode() == child.LeftDet.Card.HashCode() {
+			tri.Cards[i].Card, *top = *top, tri.Cards[i].Card
+			tri.Cards[i].FaceDown, top.FaceDown = true, false
+		}
+	}
+	for i := range tri.Stock.Cards {
+		if tri.Stock.Cards[i].HashCode() == child.LeftDet.Card.HashCode() {
+			tri.Stock.Cards[i], *top = *top, tri.Stock.Cards[i]
+		}
+	}
+	tri.Rehash()
+	tri.Play(game.Move{Kind: game.MoveDraw})
+	searcher.Search(context.Background(), tri.Observe())
+	if searcher.Roots()[0] != child {
+		t.Fatalf("the next search did not continue the kept subtree")
+	}
+	want := reuseOptions().Trajectories
+	if visits > want {
+		want = visits
+	}
+	if child.N != want {
+		t.Fatalf("kept subtree has %d visits after the search, want %d", child.N, want)
+	}
+}
+
+func TestAdvanceDropsMismatchingSubtree(t *testing.T) {
+	searcher := NewSearcher(reuseOptions())
+	searcher.Search(context.Background(), newGame(4).Observe())
+	// Draw a card that no tree dealt to the draw
+	drawn := make(map[int]bool)
+	for _, root := range searcher.Roots() {
+		drawn[drawChild(t, root).LeftDet.Card.HashCode()] = true
+	}
+	other := 0
+	for drawn[deck.FromIndex(other).HashCode()] {
+		other++
+	}
+	searcher.Advance(game.Move{Kind: game.MoveDraw}, []deck.Card{deck.FromIndex(other)})
+	if roots := searcher.Roots(); len(roots) != 0 {
+		t.Fatalf("%d subtrees kept after drawing a card no tree had", len(roots))
+	}
+}
+
+func TestResetStartsOver(t *testing.T) {
+	searcher := NewSearcher(reuseOptions())
+	searcher.Search(context.Background(), newGame(4).Observe())
+	searcher.Reset()
+	if roots := searcher.Roots(); len(roots) != 0 {
+		t.Fatalf("%d trees kept after Reset", len(roots))
+	}
+	// A new deal is searched from new trees
+	searcher.Search(context.Background(), newGame(5).Observe())
+	roots := searcher.Roots()
+	if len(roots) != reuseOptions().Determinizations {
+		t.Fatalf("%d trees, want %d", len(roots), reuseOptions().Determinizations)
+	}
+	for _, root := range roots {
+		if root.N != reuseOptions().Trajectories {
+			t.Fatalf("root has %d visits, want %d", root.N, reuseOptions().Trajectories)
+		}
+	}
+}
+
+func TestSingleMoveDropsTrees(t *testing.T) {
+	tri := newGame(4)
+	searcher := NewSearcher(reuseOptions())
+	searcher.Search(context.Background(), tri.Observe())
+	// Draw until drawing is the only move
+	for {
+		moves, _ := tri.LegalMoves()
+		if len(moves) == 1 {
+			break
+		}
+		if !tri.Play(game.Move{Kind: game.MoveDraw}) {
+			t.Fatalf("no position with a single move")
+		}
+	}
+	results, _ := searcher.Search(context.Background(), tri.Observe())
+	if len(results) != 1 {
+		t.Fatalf("results %v for a single move", results)
+	}
+	if roots := searcher.Roots(); len(roots) != 0 {
+		t.Fatalf("%d trees of an earlier position kept", len(roots))
+	}
+}
@@ -0,0 +1,111 @@
+package mcts
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// SearchStats describe where a search spent its time and how the trees grew
+type SearchStats struct {
+	// Iterations is the number of trajectories run
+	Iterations int
+	// Nodes is the number of nodes added to the trees
+	Nodes int
+	// MaxDepth and AverageDepth are the depths of the last tree nodes of the
+	// trajectories, counted from the root
+	MaxDepth     int
+	AverageDepth float64
+	// AverageRollout is the average number of moves played after the
+	// selection
+	AverageRollout float64
+	// WallTime is the duration of the search
+	WallTime time.Duration
+	// The time spent in each phase of the trajectories, summed over the
+	// threads. Expansion is the first move after the selection and
+	// Simulation the rest of the game and its evaluation. ISMCTS expands
+	// during the selection.
+	Selection       time.Duration
+	Expansion       time.Duration
+	Simulation      time.Duration
+	Backpropagation time.Duration
+	// Spread is the standard deviation of the mean reward of each move
+	// between the trees of the determinizations. Moves searched by a single
+	// tree have none.
+	Spread map[int]float64
+}
+
+func (s SearchStats) String() string {
+	return fmt.Sprintf("Iterations %d Nodes %d Depth max %d avg %.1f Rollout %.1f Time %s (selection %s expansion %s simulation %s backpropagation %s)",
+		s.Iterations, s.Nodes, s.MaxDepth, s.AverageDepth, s.AverageRollout, s.WallTime,
+		s.Selection, s.Expansion, s.Simulation, s.Backpropagation)
+}
+
+// counters are the statistics a worker collects while running trajectories
+type counters struct {
+	iterations int
+	nodes      int
+	maxDepth   int
+	depths     int
+	rollouts   int
+	selection  time.Duration
+	expansion  time.Duration
+	simulation time.Duration
+	backprop   time.Duration
+}
+
+func (c *counters) add(other counters) {
+	c.iterations += other.iterations
+	c.nodes += other.nodes
+	if other.maxDepth > c.maxDepth {
+		c.maxDepth = other.maxDepth
+	}
+	c.depths += other.depths
+	c.rollouts += other.rollouts
+	c.selection += other.selection
+	c.expansion += other.expansion
+	c.simulation += other.simulation
+	c.backprop += other.backprop
+}
+
+// addTrajectory records the depth of the last tree node of a trajectory and
+// the number of moves played after the selection
+func (c *counters) addTrajectory(node *Node, rollout int) {
+	depth := 0
+	for n := node; n.Parent != nil; n = n.Parent {
+		depth++
+	}
+	c.iterations++
+	c.depths += depth
+	if depth > c.maxDepth {
+		c.maxDepth = depth
+	}
+	c.rollouts += rollout
+}
+
+func (c counters) stats(moves map[int]moveStats, wallTime time.Duration) SearchStats {
+	stats := SearchStats{
+		Iterations:      c.iterations,
+		Nodes:           c.nodes,
+		MaxDepth:        c.maxDepth,
+		WallTime:        wallTime,
+		Selection:       c.selection,
+		Expansion:       c.expansion,
+		Simulation:      c.simulation,
+		Backpropagation: c.backprop,
+		Spread:          make(map[int]float64, len(moves)),
+	}
+	if c.iterations > 0 {
+		stats.AverageDepth = float64(c.depths) / float64(c.iterations)
+		stats.AverageRollout = float64(c.rollouts) / float64(c.iterations)
+	}
+	for move, s := range moves {
+		if s.trees < 2 {
+			continue
+		}
+		n := float64(s.trees)
+		mean := s.meanSum / n
+		stats.Spread[move] = math.Sqrt(math.Max(0, s.meanSq/n-mean*mean))
+	}
+	return stats
+}
@@ -0,0 +1,72 @@
+package mcts
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// treeSize returns the number of nodes under the root
+func treeSize(node *Node) int {
+	size := 0
+	for _, child := range node.Children {
+		size += 1 + treeSize(child)
+	}
+	return size
+}
+
+func TestStatsAddUp(t *testing.T) {
+	obs := newGame(5).Observe()
+	tests := []struct {
+		name    string
+		options Options
+	}{
+		{"determinized", Options{Threads: 2}},
+		{"tree parallel", Options{Threads: 2, TreeParallel: true, VirtualLoss: 1}},
+		{"ismcts", Options{Threads: 1, Mode: ISMCTS}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			options := test.options
+			options.Determinizations = 3
+			options.Trajectories = 50
+			options.Eval = ScoreSigmoidEval
+			options.Seed = 1
+			searcher := NewSearcher(options)
+			results, stats := searcher.Search(context.Background(), obs)
+			if want := options.Threads * 3 * 50; stats.Iterations != want {
+				t.Fatalf("%d iterations, want %d", stats.Iterations, want)
+			}
+			visits, rootVisits, nodes := 0, 0, 0
+			for _, result := range results {
+				visits += result.Visits
+			}
+			for _, root := range searcher.Roots() {
+				rootVisits += root.N
+				nodes += treeSize(root)
+			}
+			if visits != stats.Iterations || rootVisits != stats.Iterations {
+				t.Fatalf("%d iterations, the moves have %d visits and the roots %d", stats.Iterations, visits, rootVisits)
+			}
+			if stats.Nodes != nodes {
+				t.Fatalf("%d nodes counted, the trees have %d", stats.Nodes, nodes)
+			}
+			if stats.AverageDepth < 1 || stats.AverageDepth > float64(stats.MaxDepth) {
+				t.Fatalf("average depth %f, max %d", stats.AverageDepth, stats.MaxDepth)
+			}
+			if stats.AverageRollout <= 0 {
+				t.Fatalf("average rollout %f", stats.AverageRollout)
+			}
+			phases := stats.Selection + stats.Expansion + stats.Simulation + stats.Backpropagation
+			if phases <= 0 || phases > stats.WallTime*time.Duration(options.Threads) {
+				t.Fatalf("phases took %s of %s with %d threads", phases, stats.WallTime, options.Threads)
+			}
+			trees := len(searcher.Roots())
+			for move, spread := range stats.Spread {
+				if trees < 2 || spread < 0 {
+					t.Fatalf("move %d has spread %f with %d trees", move, spread, trees)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package mcts
+
+import (
+	"container/list"
+
+	"github.com/MatiasLyyra/TriPeaks/game"
+)
+
+// ttEntry holds the statistics shared by the nodes of a position
+type ttEntry struct {
+	key  uint64
+	n    int
+	x    float64
+	x2   float64
+	elem *list.Element
+}
+
+// transpositionTable maps the Zobrist hashes of positions to shared
+// statistics so that the nodes reached by different move orders learn
+// from each other. When the table is full the least recently looked up
+// entry is evicted. Nodes keep using an evicted entry, it is only no longer
+// shared with new nodes.
+type transpositionTable struct {
+	capacity int
+	entries  map[uint64]*ttEntry
+	lru      *list.List
+}
+
+func newTranspositionTable(capacity int) *transpositionTable {
+	return &transpositionTable{
+		capacity: capacity,
+		entries:  make(map[uint64]*ttEntry),
+		lru:      list.New(),
+	}
+}
+
+// lookup returns the entry of the key, adding it if it does not exist
+func (tt *transpositionTable) lookup(key uint64) *ttEntry {
+	if entry, exists := tt.entries[key]; exists {
+		tt.lru.MoveToFront(entry.elem)
+		return entry
+	}
+	if tt.lru.Len() >= tt.capacity {
+		oldest := tt.lru.Remove(tt.lru.Back()).(*ttEntry)
+		delete(tt.entries, oldest.key)
+	}
+	entry := &ttEntry{key: key}
+	entry.elem = tt.lru.PushFront(entry)
+	tt.entries[key] = entry
+	return entry
+}
+
+// positionKey returns the key of the position in the table. The Zobrist hash
+// does not cover the cards of the tableau because they are fixed in a deal,
+// but the branches of a tree deal the hidden cards independently, so the
+// face up cards are mixed into the key.
+func positionKey(tri *game.TriPeaks, salt uint64) uint64 {
+	key := tri.Hash() ^ salt
+	for pos, card := range tri.Cards {
+		if !card.Removed && !card.FaceDown && card.Card.Valid() {
+			key ^= mix(uint64(pos*52 + card.Card.Index() + 1))
+		}
+	}
+	return key
+}
+
+// mix is the finalizer of splitmix64
+func mix(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
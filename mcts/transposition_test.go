@@ -0,0 +1,116 @@
+package mcts
+
+import (
+	"context"
+	"testing"
+
+	"github.com/MatiasLyyra/TriPeaks/game"
+)
+
+func TestTranspositionTableEvictsLeastRecent(t *testing.T) {
+	tt := newTranspositionTable(2)
+	first := tt.lookup(1)
+	second := tt.lookup(2)
+	first.n = 5
+	second.n = 7
+	// Looking up the first key makes the second the least recent
+	if tt.lookup(1) != first {
+		t.Fatalf("a hit returned a new entry")
+	}
+	tt.lookup(3)
+	if len(tt.entries) != 2 || tt.lru.Len() != 2 {
+		t.Fatalf("%d entries in a table of 2", len(tt.entries))
+	}
+	if _, exists := tt.entries[2]; exists {
+		t.Fatalf("the least recent entry was not evicted")
+	}
+	if entry := tt.lookup(1); entry != first || entry.n != 5 {
+		t.Fatalf("the recent entry was evicted")
+	}
+	if entry := tt.lookup(2); entry == second || entry.n != 0 {
+		t.Fatalf("an evicted entry was returned again")
+	}
+}
+
+// playLine plays the moves on a copy of the game
+func playLine(tri *game.TriPeaks, moves ...int) *game.TriPeaks {
+	tri = tri.Copy()
+	for _, move := range moves {
+		if !tri.Play(game.MoveFromPos(move)) {
+			panic("illegal move in test line")
+		}
+	}
+	return tri
+}
+
+func TestTranspositionsShareStatistics(t *testing.T) {
+	tri := newGame(1)
+	// Both lines remove the cards 22 and 24 and draw three cards
+	first := playLine(tri, 22, -1, -1, 24, -1)
+	second := playLine(tri, 24, -1, -1, 22, -1)
+	other := playLine(tri, 22, -1, -1, -1, -1)
+	tr := &tree{root: NewNode(), tt: newTranspositionTable(10), salt: 1}
+	a, b, c := NewNode(), NewNode(), NewNode()
+	tr.attach(a, first)
+	tr.attach(b, second)
+	tr.attach(c, other)
+	if a.entry == nil || a.entry != b.entry {
+		t.Fatalf("the transpositions do not share an entry")
+	}
+	if c.entry == a.entry {
+		t.Fatalf("different positions share an entry")
+	}
+	a.update(1, 1, 1)
+	if visits, x, _ := b.Stats(); visits != 1 || x != 1 || b.N != 0 {
+		t.Fatalf("transposition has %d visits and reward %f, its own visits %d", visits, x, b.N)
+	}
+	// Trees with other salts do not share
+	salted := &tree{root: NewNode(), tt: tr.tt, salt: 2}
+	d := NewNode()
+	salted.attach(d, first)
+	if d.entry == a.entry {
+		t.Fatalf("trees with different salts share an entry")
+	}
+}
+
+// countEntries returns how many nodes of the tree have a table entry
+func countEntries(node *Node) int {
+	count := 0
+	if node.entry != nil {
+		count++
+	}
+	for _, child := range node.Children {
+		count += countEntries(child)
+	}
+	return count
+}
+
+func TestTranspositionsOption(t *testing.T) {
+	obs := newGame(2).Observe()
+	for _, capacity := range []int{0, 50} {
+		searcher := NewSearcher(Options{
+			Determinizations: 2,
+			Trajectories:     100,
+			Eval:             ScoreSigmoidEval,
+			Transpositions:   capacity,
+			Seed:             1,
+		})
+		searcher.Search(context.Background(), obs)
+		entries := 0
+		for _, root := range searcher.Roots() {
+			entries += countEntries(root)
+		}
+		if capacity == 0 {
+			if searcher.tables != nil || entries > 0 {
+				t.Fatalf("Transpositions 0 kept %d tables and %d entries", len(searcher.tables), entries)
+			}
+			continue
+		}
+		if len(searcher.tables) != 1 || entries == 0 {
+			t.Fatalf("%d tables and %d nodes with entries", len(searcher.tables), entries)
+		}
+		if size := len(searcher.tables[0].entries); size > capacity {
+			t.Fatalf("%d entries in a table of %d", size, capacity)
+		}
+	}
+}
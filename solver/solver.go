@@ -0,0 +1,204 @@
+// Package solver plays Tri Peaks with perfect information. Given a game where
+// the stock order and face-down cards are known, it decides whether the deal
+// can be won and finds the highest scoring line of play.
+package solver
+
+import "github.com/MatiasLyyra/TriPeaks/game"
+
+type Options struct {
+	// MaxNodes stops the search after this many positions have been
+	// expanded, 0 means no limit
+	MaxNodes int
+	// Score also searches for the highest scoring line. Without it only the
+	// first winning line is looked for, which is much faster.
+	Score bool
+}
+
+type Result struct {
+	Winnable bool
+	// Complete is false if the search ran out of nodes. Winnable is then
+	// only known to be true if a win was found.
+	Complete bool
+	// Moves is the best line found: the highest scoring line when
+	// Options.Score is set, otherwise the first winning line
+	Moves []game.Move
+	// Score is the final score after playing Moves
+	Score int
+	Nodes int
+}
+
+type scoreKey struct {
+	game.State
+	streak int
+}
+
+type scoreEntry struct {
+	value int
+	move  game.Move
+}
+
+type solver struct {
+	tri     *game.TriPeaks
+	options Options
+	// memo is only turned off by the tests, which check the memoized
+	// results against a plain search
+	memo     bool
+	nodes    int
+	aborted  bool
+	dead     map[game.State]struct{}
+	best     map[scoreKey]scoreEntry
+	startLen int
+	winLine  []game.Move
+}
+
+// Solve searches the game using depth first search with memoization. The game
+// is not modified.
+func Solve(tri *game.TriPeaks, options Options) Result {
+	return solve(tri, options, true)
+}
+
+func solve(tri *game.TriPeaks, options Options, memo bool) Result {
+	s := &solver{
+		tri:     tri.Copy(),
+		options: options,
+		memo:    memo,
+		dead:    make(map[game.State]struct{}),
+	}
+	// Solving relies on taking moves back for free
+	s.tri.Rules.Scoring.UndoPenalty = 0
+	s.startLen = len(s.tri.Moves())
+
+	result := Result{}
+	result.Winnable = s.win()
+	if result.Winnable {
+		result.Moves = s.winLine
+		result.Score = s.scoreOf(s.winLine)
+	}
+	if options.Score && !s.aborted {
+		s.best = make(map[scoreKey]scoreEntry)
+		s.maxScore()
+		line := s.bestLine()
+		score := s.scoreOf(line)
+		if result.Moves == nil || score > result.Score {
+			result.Moves = line
+			result.Score = score
+		}
+	}
+	result.Complete = !s.aborted
+	result.Nodes = s.nodes
+	return result
+}
+
+// Winnable reports whether the game can be won, and whether the search
+// finished within maxNodes
+func Winnable(tri *game.TriPeaks, maxNodes int) (bool, bool) {
+	result := Solve(tri, Options{MaxNodes: maxNodes})
+	return result.Winnable, result.Complete
+}
+
+func (s *solver) expand() bool {
+	if s.options.MaxNodes > 0 && s.nodes >= s.options.MaxNodes {
+		s.aborted = true
+		return false
+	}
+	s.nodes++
+	return true
+}
+
+func (s *solver) win() bool {
+	tri := s.tri
+	if tri.CardsLeft == 0 {
+		s.winLine = tri.Moves()[s.startLen:]
+		return true
+	}
+	k := tri.State()
+	if _, dead := s.dead[k]; dead && s.memo {
+		return false
+	}
+	if !s.expand() {
+		return false
+	}
+	// LegalMoves lists drawing last, so cards are always tried first
+	moves, _ := tri.LegalMoves()
+	for _, pos := range moves {
+		tri.Play(game.MoveFromPos(pos))
+		won := s.win()
+		tri.Undo()
+		if won {
+			return true
+		}
+		if s.aborted {
+			return false
+		}
+	}
+	s.dead[k] = struct{}{}
+	return false
+}
+
+// maxScore returns the most points that can still be gained from the
+// current position
+func (s *solver) maxScore() int {
+	tri := s.tri
+	if tri.CardsLeft == 0 {
+		return 0
+	}
+	k := scoreKey{
+		State:  tri.State(),
+		streak: tri.Streak,
+	}
+	if entry, exists := s.best[k]; exists && s.memo {
+		return entry.value
+	}
+	moves, _ := tri.LegalMoves()
+	if len(moves) == 0 {
+		return 0
+	}
+	if !s.expand() {
+		return 0
+	}
+	entry := scoreEntry{}
+	for i, pos := range moves {
+		move := game.MoveFromPos(pos)
+		score := tri.Score
+		tri.Play(move)
+		value := tri.Score - score + s.maxScore()
+		tri.Undo()
+		if s.aborted {
+			return 0
+		}
+		if i == 0 || value > entry.value {
+			entry = scoreEntry{
+				value: value,
+				move:  move,
+			}
+		}
+	}
+	s.best[k] = entry
+	return entry.value
+}
+
+// bestLine follows the best moves stored by maxScore
+func (s *solver) bestLine() []game.Move {
+	tri := s.tri.Copy()
+	line := make([]game.Move, 0)
+	for tri.CardsLeft > 0 {
+		entry, exists := s.best[scoreKey{
+			State:  tri.State(),
+			streak: tri.Streak,
+		}]
+		if !exists {
+			break
+		}
+		tri.Play(entry.move)
+		line = append(line, entry.move)
+	}
+	return line
+}
+
+func (s *solver) scoreOf(line []game.Move) int {
+	tri := s.tri.Copy()
+	for _, move := range line {
+		tri.Play(move)
+	}
+	return tri.Score
+}
@@ -0,0 +1,126 @@
+package solver
+
+import (
+	"testing"
+
+	"github.com/MatiasLyyra/TriPeaks/deck"
+	"github.com/MatiasLyyra/TriPeaks/game"
+)
+
+func deal(seed uint64) *game.TriPeaks {
+	stock := deck.New()
+	stock.ShuffleSeed(seed)
+	return game.NewTripeaks(*stock, game.DefaultRules())
+}
+
+// replay plays the moves on a copy of the game and returns it
+func replay(t *testing.T, tri *game.TriPeaks, moves []game.Move) *game.TriPeaks {
+	t.Helper()
+	played := tri.Copy()
+	for i, move := range moves {
+		if !played.Play(move) {
+			t.Fatalf("move %d %s of the line is illegal", i+1, move)
+		}
+	}
+	return played
+}
+
+func TestSolve(t *testing.T) {
+	tests := []struct {
+		seed     uint64
+		winnable bool
+	}{
+		{1, true},
+		{2, true},
+		{5, true},
+		{11, false},
+		{41, false},
+	}
+	for _, test := range tests {
+		tri := deal(test.seed)
+		before := tri.Notation()
+		result := Solve(tri, Options{})
+		if tri.Notation() != before {
+			t.Fatalf("seed %d: the game was modified", test.seed)
+		}
+		if result.Winnable != test.winnable || !result.Complete {
+			t.Fatalf("seed %d: winnable %t complete %t, want %t true", test.seed, result.Winnable, result.Complete, test.winnable)
+		}
+		if winnable, complete := Winnable(tri, 0); winnable != test.winnable || !complete {
+			t.Fatalf("seed %d: Winnable returned %t %t", test.seed, winnable, complete)
+		}
+		if !test.winnable {
+			if result.Moves != nil {
+				t.Fatalf("seed %d: unwinnable deal has a line %v", test.seed, result.Moves)
+			}
+			continue
+		}
+		played := replay(t, tri, result.Moves)
+		if played.CardsLeft != 0 {
+			t.Fatalf("seed %d: the winning line leaves %d cards", test.seed, played.CardsLeft)
+		}
+		if played.Score != result.Score {
+			t.Fatalf("seed %d: the line scores %d, reported %d", test.seed, played.Score, result.Score)
+		}
+	}
+}
+
+func TestSolveScore(t *testing.T) {
+	tri := deal(1)
+	first := Solve(tri, Options{})
+	best := Solve(tri, Options{Score: true})
+	if !best.Complete || !best.Winnable {
+		t.Fatalf("winnable %t complete %t", best.Winnable, best.Complete)
+	}
+	if best.Score < first.Score {
+		t.Fatalf("best score %d is lower than the first win %d", best.Score, first.Score)
+	}
+	if played := replay(t, tri, best.Moves); played.Score != best.Score {
+		t.Fatalf("the best line scores %d, reported %d", played.Score, best.Score)
+	}
+}
+
+func TestSolveMaxNodes(t *testing.T) {
+	tri := deal(41)
+	result := Solve(tri, Options{MaxNodes: 100})
+	if result.Complete {
+		t.Fatalf("search with 100 nodes completed")
+	}
+	if result.Winnable {
+		t.Fatalf("unwinnable deal reported winnable")
+	}
+	if result.Nodes > 100 {
+		t.Fatalf("search expanded %d nodes, limit 100", result.Nodes)
+	}
+	if winnable, complete := Winnable(tri, 100); winnable || complete {
+		t.Fatalf("Winnable returned %t %t", winnable, complete)
+	}
+	// A win found before the limit is still reported
+	if result := Solve(deal(1), Options{MaxNodes: 1000}); !result.Winnable || !result.Complete {
+		t.Fatalf("easy deal: winnable %t complete %t", result.Winnable, result.Complete)
+	}
+}
+
+// A memoized position must have the same outcome on every path reaching it.
+// With stock recycles that only holds if the key includes the order of the
+// discard pile, which becomes the order of the stock.
+func TestSolveRecyclingMatchesUnmemoized(t *testing.T) {
+	// Seed 20 with one recycle after some random moves. Keying the positions
+	// only by the removed cards, the stock size and the top discard makes the
+	// memoized search find a line worse by two points.
+	tri, err := game.ParseNotation("-7s,2d,8d,-9s,-4d,-4s,7d,9c,Jc,-8c,-Ah,-Kd,-Ts,-As,Qd,-3h,-9d,-Th,-7h,-7c,-2c,-5h,-Js,-2h,-Qh,-Kc,-3c,-6c " +
+		"6dQc5s6hJh3s2sQs8h 4h5h6c7h8s7c8c9hTdJsQhKc5cKs5dTcKhAd2h3c2cAhKdAsJdTs9dTh9s4c3h4dAc3d4s6s7s -4 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tri.Rules.StockRecycles = 1
+	plain := solve(tri, Options{Score: true}, false)
+	memoized := Solve(tri, Options{Score: true})
+	if memoized.Winnable != plain.Winnable || memoized.Score != plain.Score {
+		t.Fatalf("memoized search gives winnable %t score %d, plain search %t %d",
+			memoized.Winnable, memoized.Score, plain.Winnable, plain.Score)
+	}
+	if played := replay(t, tri, memoized.Moves); played.Score != memoized.Score {
+		t.Fatalf("the best line scores %d, reported %d", played.Score, memoized.Score)
+	}
+}